@@ -117,6 +117,93 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// ThrowStatement is an AST node representing a throw statement and the value being thrown.
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+// TokenLiteral returns the string literal of the token associated with this ast node.
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+
+// String returns a string representation of the Throw statement.
+func (ts *ThrowStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ts.TokenLiteral() + " ")
+	if ts.Value != nil {
+		out.WriteString(ts.Value.String())
+	}
+	out.WriteByte(';')
+
+	return out.String()
+}
+
+// TryStatement is an AST node representing a try block with its optional catch and finally blocks. At least
+// one of Catch or Finally is present.
+type TryStatement struct {
+	Token   token.Token // The 'try' token.
+	Body    *BlockStatement
+	Param   *Identifier // The identifier the caught error is bound to.
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+func (ts *TryStatement) statementNode() {}
+
+// TokenLiteral returns the string literal of the token associated with this ast node.
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+
+// String returns a string representation of the Try statement.
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(ts.Body.String())
+
+	if ts.Catch != nil {
+		out.WriteString(" catch (" + ts.Param.String() + ") ")
+		out.WriteString(ts.Catch.String())
+	}
+
+	if ts.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(ts.Finally.String())
+	}
+
+	return out.String()
+}
+
+// ForStatement is an AST node representing a for loop, which runs its body once for each item of a
+// collection.
+type ForStatement struct {
+	Token    token.Token // The 'for' token.
+	Variable *Identifier // The identifier each item is bound to.
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode() {}
+
+// TokenLiteral returns the string literal of the token associated with this ast node.
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+
+// String returns a string representation of the For statement.
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
 // ExpressionStatement is a AST node representing a statement that consists of a single expression.
 type ExpressionStatement struct {
 	Token      token.Token
@@ -263,6 +350,7 @@ type FunctionLiteral struct {
 	Token      token.Token // the 'fn' token.
 	Parameters []*Identifier
 	Body       *BlockStatement
+	Name       string // The name the function is bound to by a let statement, if any.
 }
 
 func (fl *FunctionLiteral) expressionNode() {}
@@ -351,6 +439,28 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+type SetLiteral struct {
+	Token    token.Token // The '#{' token
+	Elements []Expression
+}
+
+func (sl *SetLiteral) expressionNode()      {}
+func (sl *SetLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *SetLiteral) String() string {
+	var out bytes.Buffer
+
+	var elements []string
+	for _, el := range sl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("#{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteByte('}')
+
+	return out.String()
+}
+
 type IndexExpression struct {
 	Token token.Token // the [ token
 	Left  Expression
@@ -371,9 +481,49 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// SliceExpression is an AST node representing a slice of an array or string. Start, End and Step are nil
+// when they are left out.
+type SliceExpression struct {
+	Token token.Token // the [ token
+	Left  Expression
+	Start Expression
+	End   Expression
+	Step  Expression
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	bound := func(exp Expression) string {
+		if exp == nil {
+			return ""
+		}
+		return exp.String()
+	}
+
+	out.WriteByte('(')
+	out.WriteString(se.Left.String())
+	out.WriteByte('[')
+	out.WriteString(bound(se.Start) + ":" + bound(se.End))
+	if se.Step != nil {
+		out.WriteString(":" + se.Step.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
 type HashLiteral struct {
 	Token token.Token // The '{' token
-	Pairs map[Expression]Expression
+	Pairs []HashPair  // In the order they are written.
+}
+
+// HashPair is a key of a HashLiteral and the value it maps to.
+type HashPair struct {
+	Key   Expression
+	Value Expression
 }
 
 func (hl *HashLiteral) expressionNode()      {}
@@ -382,8 +532,8 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	var pairs []string
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
 	}
 
 	out.WriteByte('{')
@@ -0,0 +1,223 @@
+package diagnostic
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/butlermatt/monlox/object"
+	"github.com/butlermatt/monlox/parser"
+	"github.com/butlermatt/monlox/token"
+)
+
+const (
+	reset = "\x1b[0m"
+	bold  = "\x1b[1m"
+	red   = "\x1b[1;31m"
+	blue  = "\x1b[1;34m"
+)
+
+// Diagnostic is an error found in a program, located at a span of one of the lines of its source.
+type Diagnostic struct {
+	Title   string // The category of the error, such as the kind of a runtime error.
+	Message string
+	Line    int
+	Column  int // Byte offset within Line the span starts at, starting at 1. 0 if unknown.
+	Length  int // Length of the span in bytes.
+	Hint    string
+}
+
+// FromParseError returns a Diagnostic describing a syntax error.
+func FromParseError(err *parser.ParseError) Diagnostic {
+	d := Diagnostic{
+		Title:   "syntax error",
+		Message: err.Message,
+		Line:    err.Line,
+		Column:  err.Column,
+		Length:  tokenLength(err.Found),
+	}
+
+	var expected []string
+	for _, t := range err.Expected {
+		expected = append(expected, describeToken(t))
+	}
+
+	switch {
+	case err.Found.Type == token.UTSTRING:
+		d.Hint = "add a closing \" to end the string"
+	case len(expected) == 1:
+		d.Hint = "expected " + expected[0] + " here"
+	case len(expected) > 1:
+		d.Hint = "expected one of " + strings.Join(expected, ", ") + " here"
+	}
+
+	return d
+}
+
+// describeToken returns how a token of type t is described to the user.
+func describeToken(t token.TokenType) string {
+	switch t {
+	case token.IDENT:
+		return "an identifier"
+	case token.NUM:
+		return "a number"
+	case token.STRING:
+		return "a string"
+	}
+
+	// Keywords are named by their upper case token types, so are shown as they are written.
+	return "`" + strings.ToLower(string(t)) + "`"
+}
+
+// FromError returns a Diagnostic describing a runtime error.
+func FromError(err *object.Error) Diagnostic {
+	d := Diagnostic{
+		Title:   string(err.Kind),
+		Message: err.Message,
+		Line:    err.Line,
+		Column:  err.Column,
+		Length:  len(err.Operator),
+	}
+
+	switch err.Kind {
+	case object.NameError:
+		if len(err.Operands) == 1 {
+			d.Length = len(err.Operands[0].Inspect())
+		}
+		d.Hint = "define it with `let` before it is used"
+	case object.ArityError:
+		d.Hint = "check the parameters of the function being called"
+	case object.RecursionError:
+		d.Hint = "check that the recursion has a reachable base case"
+	case object.TypeError:
+		if isMismatch(err) {
+			d.Hint = fmt.Sprintf("both operands of `%s` must be the same type", err.Operator)
+		}
+	}
+
+	return d
+}
+
+// sameTypeOperators are the infix operators which apply to two operands of the same type.
+var sameTypeOperators = map[string]bool{
+	token.PLUS:      true,
+	token.MINUS:     true,
+	token.ASTERISK:  true,
+	token.SLASH:     true,
+	token.PIPE:      true,
+	token.AMPERSAND: true,
+	token.LT:        true,
+	token.GT:        true,
+	token.LT_EQ:     true,
+	token.GT_EQ:     true,
+	token.EQ_EQ:     true,
+	token.NOT_EQ:    true,
+	"and":           true,
+	"or":            true,
+}
+
+// isMismatch reports whether err is for an infix operator which applies to operands of the same type being
+// given operands of different types. Other operators, such as indexing, take operands of different types.
+func isMismatch(err *object.Error) bool {
+	return sameTypeOperators[err.Operator] && len(err.Operands) == 2 &&
+		err.Operands[0].Type() != err.Operands[1].Type()
+}
+
+func tokenLength(tok token.Token) int {
+	switch tok.Type {
+	case token.STRING:
+		return len(tok.Literal) + 2
+	case token.UTSTRING:
+		return len(tok.Literal) + 1
+	}
+
+	return len(tok.Literal)
+}
+
+// Renderer writes diagnostics with a snippet of the source they were found in.
+type Renderer struct {
+	Name  string // Name of the source, such as the path of the file it was read from.
+	Color bool   // Whether to highlight the output with ANSI escape codes.
+
+	lines []string
+}
+
+// NewRenderer returns a Renderer for diagnostics found in source.
+func NewRenderer(name, source string, color bool) *Renderer {
+	return &Renderer{Name: name, Color: color, lines: strings.Split(source, "\n")}
+}
+
+// Render writes d to w, underlining its span in the offending line of source.
+func (r *Renderer) Render(w io.Writer, d Diagnostic) {
+	var out bytes.Buffer
+
+	gutter := strings.Repeat(" ", len(strconv.Itoa(d.Line)))
+
+	out.WriteString(r.paint(red, "error["+d.Title+"]"))
+	out.WriteString(r.paint(bold, ": "+d.Message) + "\n")
+
+	location := fmt.Sprintf("%s:%d", r.Name, d.Line)
+	if d.Column > 0 {
+		location += fmt.Sprintf(":%d", d.Column)
+	}
+	out.WriteString(gutter + r.paint(blue, "--> ") + location + "\n")
+
+	if d.Line > 0 && d.Line <= len(r.lines) {
+		line := strings.TrimRight(r.lines[d.Line-1], "\r")
+
+		out.WriteString(gutter + r.paint(blue, " |") + "\n")
+		out.WriteString(r.paint(blue, strconv.Itoa(d.Line)+" |") + " " + line + "\n")
+		out.WriteString(gutter + r.paint(blue, " |") + " " + r.underline(line, d) + "\n")
+	}
+
+	if d.Hint != "" {
+		out.WriteString(gutter + r.paint(blue, " = ") + r.paint(bold, "hint") + ": " + d.Hint + "\n")
+	}
+
+	w.Write(out.Bytes())
+}
+
+// underline returns the carets marking the span of d within line. If the column is not known the whole
+// line is marked.
+func (r *Renderer) underline(line string, d Diagnostic) string {
+	start, end := d.Column-1, d.Column-1+d.Length
+	if d.Column < 1 {
+		start = len(line) - len(strings.TrimLeft(line, " \t"))
+		end = len(line)
+	}
+
+	if start > len(line) {
+		start = len(line)
+	}
+	if end > len(line) {
+		end = len(line)
+	}
+
+	// Tabs are kept so the carets line up with the source however wide the terminal shows them.
+	var pad strings.Builder
+	for _, ch := range line[:start] {
+		if ch == '\t' {
+			pad.WriteByte('\t')
+		} else {
+			pad.WriteByte(' ')
+		}
+	}
+
+	width := utf8.RuneCountInString(line[start:end])
+	if width < 1 {
+		width = 1
+	}
+
+	return pad.String() + r.paint(red, strings.Repeat("^", width))
+}
+
+func (r *Renderer) paint(style, s string) string {
+	if !r.Color {
+		return s
+	}
+
+	return style + s + reset
+}
@@ -0,0 +1,146 @@
+package diagnostic
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/butlermatt/monlox/evaluator"
+	"github.com/butlermatt/monlox/lexer"
+	"github.com/butlermatt/monlox/object"
+	"github.com/butlermatt/monlox/parser"
+)
+
+func TestRenderParseError(t *testing.T) {
+	input := "let x = 5;\nlet y = (x + 2;\n"
+
+	p := parser.New(lexer.New(input))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("wrong number of parse errors. expected=%d, got=%d", 1, len(errors))
+	}
+
+	var out bytes.Buffer
+	NewRenderer("test.mlx", input, false).Render(&out, FromParseError(errors[0]))
+
+	expected := `error[syntax error]: expected next token to be ), got ; instead
+ --> test.mlx:2:15
+  |
+2 | let y = (x + 2;
+  |               ^
+  = hint: expected ` + "`)`" + ` here
+`
+
+	if out.String() != expected {
+		t.Errorf("wrong rendering.\nexpected=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
+
+func TestRenderRuntimeError(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let a = 1;\n\tlet b = a + \"two\";",
+			`error[TypeError]: type mismatch: NUMBER + STRING
+ --> test.mlx:2:12
+  |
+2 | 	let b = a + "two";
+  | 	          ^
+  = hint: both operands of ` + "`+`" + ` must be the same type
+`,
+		},
+		{
+			"let s = \"é\"; foo",
+			`error[NameError]: identifier not found: foo
+ --> test.mlx:1:15
+  |
+1 | let s = "é"; foo
+  |              ^^^
+  = hint: define it with ` + "`let`" + ` before it is used
+`,
+		},
+		{
+			"let f = fn(x) { x };\n  f(1, 2)",
+			`error[ArityError]: wrong number of arguments. expected=1, got=2
+ --> test.mlx:2:4
+  |
+2 |   f(1, 2)
+  |    ^
+  = hint: check the parameters of the function being called
+`,
+		},
+		{
+			"let n = 1; repeat(\"a\", -n)",
+			`error[ValueError]: ` + "`repeat`" + ` count must not be negative, got=-1
+ --> test.mlx:1:18
+  |
+1 | let n = 1; repeat("a", -n)
+  |                  ^
+`,
+		},
+		{
+			"let f = fn(x) {\n  len(x) };\nf(1)",
+			`error[TypeError]: argument to ` + "`len`" + ` not supported. got=NUMBER
+ --> test.mlx:2:6
+  |
+2 |   len(x) };
+  |      ^
+`,
+		},
+		{
+			"let x = 1; throw \"oops\"",
+			`error[Error]: oops
+ --> test.mlx:1:12
+  |
+1 | let x = 1; throw "oops"
+  |            ^
+`,
+		},
+		{
+			"[1][\"a\"]",
+			`error[TypeError]: index operator not supported: ARRAY
+ --> test.mlx:1
+  |
+1 | [1]["a"]
+  | ^^^^^^^^
+`,
+		},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		evaluated := evaluator.Eval(program, object.NewEnvironment())
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Fatalf("test %d: object is unexpected type. expected=*object.Error, got=%T (%+[2]v)", i, evaluated)
+		}
+
+		var out bytes.Buffer
+		NewRenderer("test.mlx", tt.input, false).Render(&out, FromError(errObj))
+
+		if out.String() != tt.expected {
+			t.Errorf("test %d: wrong rendering.\nexpected=\n%s\ngot=\n%s", i, tt.expected, out.String())
+		}
+	}
+}
+
+func TestRenderColor(t *testing.T) {
+	d := Diagnostic{Title: "TypeError", Message: "oops", Line: 1, Column: 1, Length: 1}
+
+	var out bytes.Buffer
+	NewRenderer("<repl>", "x", true).Render(&out, d)
+
+	expected := red + "error[TypeError]" + reset + bold + ": oops" + reset + "\n" +
+		" " + blue + "--> " + reset + "<repl>:1:1\n" +
+		" " + blue + " |" + reset + "\n" +
+		blue + "1 |" + reset + " x\n" +
+		" " + blue + " |" + reset + " " + red + "^" + reset + "\n"
+
+	if out.String() != expected {
+		t.Errorf("wrong rendering.\nexpected=%q\ngot=     %q", expected, out.String())
+	}
+}
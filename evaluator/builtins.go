@@ -3,11 +3,12 @@ package evaluator
 import (
 	"fmt"
 	"github.com/butlermatt/monlox/object"
+	"unicode/utf8"
 )
 
 var builtins = map[string]*object.Builtin{
 	"len": {
-		Fn: func(line int, args ...object.Object) object.Object {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
 			if e := expectNArgs(line, 1, args); e != nil {
 				return e
 			}
@@ -16,20 +17,24 @@ var builtins = map[string]*object.Builtin{
 			case *object.Array:
 				return &object.Number{Value: float32(len(arg.Elements))}
 			case *object.String:
-				return &object.Number{Value: float32(len(arg.Value))}
+				return &object.Number{Value: float32(utf8.RuneCountInString(arg.Value))}
+			case *object.Set:
+				return &object.Number{Value: float32(arg.Len())}
+			case *object.Hash:
+				return &object.Number{Value: float32(arg.Len())}
 			}
 
-			return newError(line, "argument to `len` not supported. got=%s", args[0].Type())
+			return newArgumentError(line, "argument to `len` not supported. got=%s", args[0])
 		},
 	},
 	"first": {
-		Fn: func(line int, args ...object.Object) object.Object {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
 			if e := expectNArgs(line, 1, args); e != nil {
 				return e
 			}
 
 			if args[0].Type() != object.ARRAY {
-				return newError(line, "argument to `first` must be ARRAY, got=%s", args[0].Type())
+				return newArgumentError(line, "argument to `first` must be ARRAY, got=%s", args[0])
 			}
 
 			arg := args[0].(*object.Array)
@@ -41,13 +46,13 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"last": {
-		Fn: func(line int, args ...object.Object) object.Object {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
 			if e := expectNArgs(line, 1, args); e != nil {
 				return e
 			}
 
 			if args[0].Type() != object.ARRAY {
-				return newError(line, "argument to `last` must be ARRAY, got=%s", args[0].Type())
+				return newArgumentError(line, "argument to `last` must be ARRAY, got=%s", args[0])
 			}
 
 			arg := args[0].(*object.Array)
@@ -59,13 +64,13 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"rest": {
-		Fn: func(line int, args ...object.Object) object.Object {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
 			if e := expectNArgs(line, 1, args); e != nil {
 				return e
 			}
 
 			if args[0].Type() != object.ARRAY {
-				return newError(line, "argument to `rest` must be ARRAY, got=%s", args[0].Type())
+				return newArgumentError(line, "argument to `rest` must be ARRAY, got=%s", args[0])
 			}
 
 			arr := args[0].(*object.Array)
@@ -79,13 +84,13 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"push": {
-		Fn: func(line int, args ...object.Object) object.Object {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
 			if e := expectNArgs(line, 2, args); e != nil {
 				return e
 			}
 
 			if args[0].Type() != object.ARRAY {
-				return newError(line, "first argument to `push` must be ARRAY, got=%s", args[0].Type())
+				return newArgumentError(line, "first argument to `push` must be ARRAY, got=%s", args[0])
 			}
 
 			arr := args[0].(*object.Array)
@@ -98,10 +103,53 @@ var builtins = map[string]*object.Builtin{
 			return &object.Array{Elements: newEls}
 		},
 	},
+	"add": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 2, args); e != nil {
+				return e
+			}
+
+			set, ok := args[0].(*object.Set)
+			if !ok {
+				return newArgumentError(line, "first argument to `add` must be SET, got=%s", args[0])
+			}
+
+			if _, ok := args[1].(object.Hashable); !ok {
+				return newSetElementError(line, args[1])
+			}
+
+			result := object.NewSet(set.Elements()...)
+			result.Add(args[1])
+
+			return result
+		},
+	},
+	"remove": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 2, args); e != nil {
+				return e
+			}
+
+			set, ok := args[0].(*object.Set)
+			if !ok {
+				return newArgumentError(line, "first argument to `remove` must be SET, got=%s", args[0])
+			}
+
+			result := object.NewSet(set.Elements()...)
+			result.Remove(args[1])
+
+			return result
+		},
+	},
 	"puts": {
-		Fn: func(line int, args ...object.Object) object.Object {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			oc, ok := c.(OutputCaller)
+			if !ok {
+				return newUnsupportedError(line, "puts")
+			}
+
 			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+				fmt.Fprintln(oc.Output(), arg.Inspect())
 			}
 
 			return Null
@@ -109,10 +157,51 @@ var builtins = map[string]*object.Builtin{
 	},
 }
 
+// modules are the groups of builtins defined alongside the core builtins above.
+var modules = []map[string]*object.Builtin{
+	collectionBuiltins,
+	stringBuiltins,
+	mathBuiltins,
+	hashBuiltins,
+	jsonBuiltins,
+	typeBuiltins,
+	formatBuiltins,
+	fileBuiltins,
+	regexBuiltins,
+	timeBuiltins,
+}
+
+func init() {
+	for _, module := range modules {
+		for name, builtin := range module {
+			if _, ok := builtins[name]; ok {
+				panic("builtin defined twice: " + name)
+			}
+			builtins[name] = builtin
+		}
+	}
+}
+
 func expectNArgs(line, expect int, args []object.Object) *object.Error {
 	if len(args) != expect {
-		return newError(line, "wrong number of arguments. expected=%d, got=%d", expect, len(args))
+		return newError(line, object.ArityError, "wrong number of arguments. expected=%d, got=%d", expect, len(args))
+	}
+
+	return nil
+}
+
+// expectArgsBetween returns an ArityError unless there are at least min and at most max args.
+func expectArgsBetween(line, min, max int, args []object.Object) *object.Error {
+	if len(args) < min || len(args) > max {
+		return newError(line, object.ArityError, "wrong number of arguments. expected=%d to %d, got=%d", min, max, len(args))
 	}
 
 	return nil
 }
+
+// newArgumentError returns a TypeError for an argument of the wrong type. format is passed the type of arg.
+func newArgumentError(line int, format string, arg object.Object) *object.Error {
+	errObj := newError(line, object.TypeError, format, arg.Type())
+	errObj.Operands = []object.Object{arg}
+	return errObj
+}
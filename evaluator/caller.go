@@ -0,0 +1,48 @@
+package evaluator
+
+import (
+	"context"
+	"github.com/butlermatt/monlox/object"
+	"io"
+	"math/rand"
+)
+
+// Builtins needing more of the host than calling functions ask their object.Caller for it through the
+// interfaces below, which the Evaluator implements. A builtin whose Caller does not implement the interface
+// it needs returns an error, so builtins can also be called by hosts providing only some of them.
+
+// RandomCaller is a Caller providing the source of random numbers.
+type RandomCaller interface {
+	object.Caller
+	Random() *rand.Rand
+}
+
+// OutputCaller is a Caller providing the writer the program prints to.
+type OutputCaller interface {
+	object.Caller
+	Output() io.Writer
+}
+
+// FileCaller is a Caller providing the filesystem of the file builtins, or nil if scripts may not use files.
+type FileCaller interface {
+	object.Caller
+	FileSystem() FS
+}
+
+// ClockCaller is a Caller providing the clock the program tells the time by.
+type ClockCaller interface {
+	object.Caller
+	TimeSource() Clock
+}
+
+// ContextCaller is a Caller providing the context of the evaluation, which is done once the host has
+// cancelled it or its deadline has passed.
+type ContextCaller interface {
+	object.Caller
+	Context() context.Context
+}
+
+// newUnsupportedError returns the error a builtin returns when its Caller does not provide a service it needs.
+func newUnsupportedError(line int, name string) *object.Error {
+	return newError(line, object.InternalError, "`%s` is not supported by this caller", name)
+}
@@ -0,0 +1,60 @@
+package evaluator
+
+import (
+	"github.com/butlermatt/monlox/object"
+	"testing"
+	"time"
+)
+
+// callOnlyCaller is a Caller providing none of the services of an Evaluator.
+type callOnlyCaller struct{}
+
+func (callOnlyCaller) Call(line int, fn object.Object, args ...object.Object) object.Object {
+	return Null
+}
+
+// clockCaller is a Caller providing only a clock.
+type clockCaller struct {
+	callOnlyCaller
+	clock Clock
+}
+
+func (c clockCaller) TimeSource() Clock {
+	return c.clock
+}
+
+func TestBuiltinsWithoutServices(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []object.Object
+		expected string
+	}{
+		{"random", nil, "InternalError line 3: `random` is not supported by this caller"},
+		{"puts", nil, "InternalError line 3: `puts` is not supported by this caller"},
+		{"print", []object.Object{&object.String{Value: "x"}}, "InternalError line 3: `print` is not supported by this caller"},
+		{"read_file", []object.Object{&object.String{Value: "x"}}, "InternalError line 3: `read_file` is not supported by this caller"},
+		{"now", nil, "InternalError line 3: `now` is not supported by this caller"},
+		{"unix", nil, "InternalError line 3: `unix` is not supported by this caller"},
+		{"sleep", []object.Object{&object.Number{Value: 1}}, "InternalError line 3: `sleep` is not supported by this caller"},
+	}
+
+	for _, tt := range tests {
+		result := builtins[tt.name].Fn(callOnlyCaller{}, 3, tt.args...)
+		if result.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.name, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestSleepWithoutContext(t *testing.T) {
+	clock := NewManualClock(testStart)
+
+	result := builtins["sleep"].Fn(clockCaller{clock: clock}, 1, &object.Number{Value: 60})
+	if result != Null {
+		t.Fatalf("sleep returned %s", result.Inspect())
+	}
+
+	if got := clock.Now(); !got.Equal(testStart.Add(time.Minute)) {
+		t.Errorf("wrong time after sleeping. got=%s", got)
+	}
+}
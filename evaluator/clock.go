@@ -0,0 +1,72 @@
+package evaluator
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time for the time builtins.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Sleep waits for d to pass, returning early if ctx is done.
+	Sleep(ctx context.Context, d time.Duration)
+}
+
+// systemClock is the Clock of the system, used if the host does not give an Evaluator one.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// ManualClock is a Clock whose time only moves when it is advanced, either by the host or by sleeping,
+// which returns at once. It is safe for concurrent use.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock returns a ManualClock stopped at now.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Set moves the clock to now.
+func (c *ManualClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+// Advance moves the clock forward by d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// Sleep advances the clock by d without waiting, unless ctx is already done.
+func (c *ManualClock) Sleep(ctx context.Context, d time.Duration) {
+	if ctx.Err() == nil {
+		c.Advance(d)
+	}
+}
@@ -0,0 +1,305 @@
+package evaluator
+
+import (
+	"github.com/butlermatt/monlox/object"
+	"sort"
+)
+
+// collectionBuiltins work with the items of arrays and other collections, most of them by calling a
+// function given as their last argument. Collections are visited in the same order as a for loop visits them.
+var collectionBuiltins = map[string]*object.Builtin{
+	"map": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 2, args); e != nil {
+				return e
+			}
+
+			items, errObj := iterableArg(line, "map", args[0])
+			if errObj != nil {
+				return errObj
+			}
+			if errObj := expectFunction(line, "second argument to `map`", args[1]); errObj != nil {
+				return errObj
+			}
+
+			var results []object.Object
+			for _, item := range items {
+				result := c.Call(line, args[1], item)
+				if isError(result) {
+					return result
+				}
+				results = append(results, result)
+			}
+
+			return &object.Array{Elements: results}
+		},
+	},
+	"filter": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 2, args); e != nil {
+				return e
+			}
+
+			items, errObj := iterableArg(line, "filter", args[0])
+			if errObj != nil {
+				return errObj
+			}
+			if errObj := expectFunction(line, "second argument to `filter`", args[1]); errObj != nil {
+				return errObj
+			}
+
+			var kept []object.Object
+			for _, item := range items {
+				result := c.Call(line, args[1], item)
+				if isError(result) {
+					return result
+				}
+				if isTruthy(result) {
+					kept = append(kept, item)
+				}
+			}
+
+			return &object.Array{Elements: kept}
+		},
+	},
+	"reduce": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectArgsBetween(line, 2, 3, args); e != nil {
+				return e
+			}
+
+			items, errObj := iterableArg(line, "reduce", args[0])
+			if errObj != nil {
+				return errObj
+			}
+			if errObj := expectFunction(line, "second argument to `reduce`", args[1]); errObj != nil {
+				return errObj
+			}
+
+			var acc object.Object
+			if len(args) == 3 {
+				acc = args[2]
+			} else if len(items) > 0 {
+				acc, items = items[0], items[1:]
+			} else {
+				return newError(line, object.ValueError, "`reduce` of empty %s with no initial value", args[0].Type())
+			}
+
+			for _, item := range items {
+				acc = c.Call(line, args[1], acc, item)
+				if isError(acc) {
+					return acc
+				}
+			}
+
+			return acc
+		},
+	},
+	"any": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			return evalQuantifier(c, line, "any", true, args)
+		},
+	},
+	"all": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			return evalQuantifier(c, line, "all", false, args)
+		},
+	},
+	// sort returns a sorted copy of an array. Without a comparator it sorts numbers or strings in ascending
+	// order. A comparator is called with two elements and must return a BOOLEAN, true if the first sorts
+	// before the second; elements it does not order keep their order.
+	"sort": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectArgsBetween(line, 1, 2, args); e != nil {
+				return e
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newArgumentError(line, "first argument to `sort` must be ARRAY, got=%s", args[0])
+			}
+
+			less := func(a, b object.Object) object.Object { return compareValues(line, a, b) }
+			if len(args) == 2 {
+				if errObj := expectFunction(line, "second argument to `sort`", args[1]); errObj != nil {
+					return errObj
+				}
+				less = func(a, b object.Object) object.Object { return c.Call(line, args[1], a, b) }
+			}
+
+			sorted := make([]object.Object, len(arr.Elements))
+			copy(sorted, arr.Elements)
+
+			// sort cannot be stopped part way, so once an error is raised the remaining comparisons are
+			// skipped and the error is returned when it finishes.
+			var errObj object.Object
+			sort.SliceStable(sorted, func(i, j int) bool {
+				if errObj != nil {
+					return false
+				}
+
+				result := less(sorted[i], sorted[j])
+				if isError(result) {
+					errObj = result
+					return false
+				}
+				before, ok := result.(*object.Boolean)
+				if !ok {
+					errObj = newError(line, object.TypeError, "comparator given to `sort` must return BOOLEAN, got=%s", result.Type())
+					return false
+				}
+				return before.Value
+			})
+
+			if errObj != nil {
+				return errObj
+			}
+
+			return &object.Array{Elements: sorted}
+		},
+	},
+	"reverse": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newArgumentError(line, "argument to `reverse` must be ARRAY, got=%s", args[0])
+			}
+
+			length := len(arr.Elements)
+			reversed := make([]object.Object, length)
+			for i, el := range arr.Elements {
+				reversed[length-1-i] = el
+			}
+
+			return &object.Array{Elements: reversed}
+		},
+	},
+	"zip": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return newError(line, object.ArityError, "wrong number of arguments. expected at least 1, got=0")
+			}
+
+			var lists [][]object.Object
+			shortest := -1
+			for _, arg := range args {
+				items, errObj := iterableArg(line, "zip", arg)
+				if errObj != nil {
+					return errObj
+				}
+
+				lists = append(lists, items)
+				if shortest < 0 || len(items) < shortest {
+					shortest = len(items)
+				}
+			}
+
+			tuples := make([]object.Object, shortest)
+			for i := range tuples {
+				tuple := make([]object.Object, len(lists))
+				for j, items := range lists {
+					tuple[j] = items[i]
+				}
+				tuples[i] = &object.Array{Elements: tuple}
+			}
+
+			return &object.Array{Elements: tuples}
+		},
+	},
+	"enumerate": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			items, errObj := iterableArg(line, "enumerate", args[0])
+			if errObj != nil {
+				return errObj
+			}
+
+			pairs := make([]object.Object, len(items))
+			for i, item := range items {
+				pairs[i] = &object.Array{Elements: []object.Object{&object.Number{Value: float32(i)}, item}}
+			}
+
+			return &object.Array{Elements: pairs}
+		},
+	},
+}
+
+// evalQuantifier reports whether any, or all, items of the collection in args are truthy, or if a function
+// is also given whether it returns a truthy value for them. want is the result that stops the search early:
+// true for any and false for all.
+func evalQuantifier(c object.Caller, line int, name string, want bool, args []object.Object) object.Object {
+	if e := expectArgsBetween(line, 1, 2, args); e != nil {
+		return e
+	}
+
+	items, errObj := iterableArg(line, name, args[0])
+	if errObj != nil {
+		return errObj
+	}
+	if len(args) == 2 {
+		if errObj := expectFunction(line, "second argument to `"+name+"`", args[1]); errObj != nil {
+			return errObj
+		}
+	}
+
+	for _, item := range items {
+		result := item
+		if len(args) == 2 {
+			result = c.Call(line, args[1], item)
+			if isError(result) {
+				return result
+			}
+		}
+
+		if isTruthy(result) == want {
+			return nativeBooltoObject(want)
+		}
+	}
+
+	return nativeBooltoObject(!want)
+}
+
+// compareValues reports whether a sorts before b when sorting without a comparator. Only numbers and
+// strings can be compared, and only with values of their own type.
+func compareValues(line int, a, b object.Object) object.Object {
+	switch a := a.(type) {
+	case *object.Number:
+		if b, ok := b.(*object.Number); ok {
+			return nativeBooltoObject(a.Value < b.Value)
+		}
+	case *object.String:
+		if b, ok := b.(*object.String); ok {
+			return nativeBooltoObject(a.Value < b.Value)
+		}
+	}
+
+	errObj := newError(line, object.TypeError, "cannot compare %s with %s", a.Type(), b.Type())
+	errObj.Operands = []object.Object{a, b}
+	return errObj
+}
+
+// iterableArg returns the items of the first argument to the builtin name, as visited by a for loop.
+func iterableArg(line int, name string, arg object.Object) ([]object.Object, *object.Error) {
+	items, ok := iterate(arg)
+	if !ok {
+		return nil, newArgumentError(line, "argument to `"+name+"` must be iterable, got=%s", arg)
+	}
+
+	return items, nil
+}
+
+// expectFunction returns a TypeError unless arg can be called. desc describes the argument in the error.
+func expectFunction(line int, desc string, arg object.Object) *object.Error {
+	if arg.Type() != object.FUNCTION && arg.Type() != object.BUILTIN {
+		return newArgumentError(line, desc+" must be FUNCTION, got=%s", arg)
+	}
+
+	return nil
+}
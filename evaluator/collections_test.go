@@ -0,0 +1,98 @@
+package evaluator
+
+import (
+	"github.com/butlermatt/monlox/lexer"
+	"github.com/butlermatt/monlox/object"
+	"github.com/butlermatt/monlox/parser"
+	"testing"
+)
+
+func TestCollectionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"map([1, 2, 3], fn(x) { x * 2 })", "[2, 4, 6]"},
+		{"map([], fn(x) { x })", "[]"},
+		{`map(#{"a", "b"}, fn(x) { x + x })`, "[aa, bb]"},
+		{`map({"a": 1, "b": 2}, fn(k) { k })`, "[a, b]"},
+		{`map(["a", [1, 2]], len)`, "[1, 2]"},
+		{"let add = fn(n) { fn(x) { x + n } }; map([1, 2], add(10))", "[11, 12]"},
+		{"filter([1, 2, 3, 4], fn(x) { x > 2 })", "[3, 4]"},
+		{"filter([1, 2], fn(x) { false })", "[]"},
+		{"reduce([1, 2, 3, 4], fn(acc, x) { acc + x })", "10"},
+		{"reduce([1, 2, 3], fn(acc, x) { acc + x }, 10)", "16"},
+		{"reduce([], fn(acc, x) { acc + x }, 0)", "0"},
+		{`reduce(["a", "b"], fn(acc, x) { push(acc, x) }, [])`, "[a, b]"},
+		{"any([1, 2, 3], fn(x) { x > 2 })", "true"},
+		{"any([1, 2, 3], fn(x) { x > 3 })", "false"},
+		{"any([false, 1])", "true"},
+		{"any([])", "false"},
+		{"all([1, 2, 3], fn(x) { x > 0 })", "true"},
+		{"all([1, 2, 3], fn(x) { x > 1 })", "false"},
+		{"all([true, false])", "false"},
+		{"all([])", "true"},
+		{"sort([3, 1, 2])", "[1, 2, 3]"},
+		{`sort(["b", "c", "a"])`, "[a, b, c]"},
+		{"sort([3, 1, 2], fn(a, b) { a > b })", "[3, 2, 1]"},
+		{`sort([[2, "b"], [1, "a"], [2, "a"]], fn(a, b) { a[0] < b[0] })`, "[[1, a], [2, b], [2, a]]"},
+		{"let xs = [2, 1]; sort(xs); xs", "[2, 1]"},
+		{"sort([])", "[]"},
+		{"reverse([1, 2, 3])", "[3, 2, 1]"},
+		{"reverse([])", "[]"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+		{"zip([1, 2], [3, 4], [5, 6])", "[[1, 3, 5], [2, 4, 6]]"},
+		{"zip([1])", "[[1]]"},
+		{`enumerate(["a", "b"])`, "[[0, a], [1, b]]"},
+		{`enumerate("hé")`, "[[0, h], [1, é]]"},
+		{"map(1, fn(x) { x })", "TypeError line 1: argument to `map` must be iterable, got=NUMBER"},
+		{"map([1], 1)", "TypeError line 1: second argument to `map` must be FUNCTION, got=NUMBER"},
+		{"map([1])", "ArityError line 1: wrong number of arguments. expected=2, got=1"},
+		{"map([1], fn(x, y) { x })", "ArityError line 1: wrong number of arguments. expected=2, got=1"},
+		{"map([1], fn(x) { x + true })", "TypeError line 1: type mismatch: NUMBER + BOOLEAN"},
+		{`filter([1], fn(x) { throw "bad" })`, "Error line 1: bad"},
+		{"reduce([], fn(acc, x) { acc })", "ValueError line 1: `reduce` of empty ARRAY with no initial value"},
+		{"reduce([1], fn(acc, x) { acc }, 1, 2)", "ArityError line 1: wrong number of arguments. expected=2 to 3, got=4"},
+		{`sort([1, "a"])`, "TypeError line 1: cannot compare STRING with NUMBER"},
+		{`sort([1, 2], fn(a, b) { a + "" })`, "TypeError line 1: type mismatch: NUMBER + STRING"},
+		{"sort([1, 2], fn(a, b) { a - b })", "TypeError line 1: comparator given to `sort` must return BOOLEAN, got=NUMBER"},
+		{"sort(#{1})", "TypeError line 1: first argument to `sort` must be ARRAY, got=SET"},
+		{"reverse(1)", "TypeError line 1: argument to `reverse` must be ARRAY, got=NUMBER"},
+		{"zip()", "ArityError line 1: wrong number of arguments. expected at least 1, got=0"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestCallbacksShareBudget(t *testing.T) {
+	program := parser.New(lexer.New("map([1, 2, 3, 4, 5], fn(x) { x })")).ParseProgram()
+
+	e := New()
+	e.MaxSteps = 4
+
+	evaluated := e.Eval(program, object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Kind != object.StepLimitError {
+		t.Errorf("callback steps not counted. got=%s", evaluated.Inspect())
+	}
+}
+
+func TestCallbackErrorStack(t *testing.T) {
+	input := `let check = fn(x) { x + true };
+map([1], check)`
+
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is unexpected type. expected=*object.Error, got=%T (%+[1]v)", evaluated)
+	}
+
+	if len(errObj.Stack) != 1 || errObj.Stack[0].Function != "check" || errObj.Stack[0].Line != 2 {
+		t.Errorf("wrong stack for error raised in callback. got=%+v", errObj.Stack)
+	}
+}
@@ -1,9 +1,18 @@
 package evaluator
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/butlermatt/monlox/ast"
 	"github.com/butlermatt/monlox/object"
+	"github.com/butlermatt/monlox/suggest"
+	"github.com/butlermatt/monlox/token"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
 )
 
 var (
@@ -12,74 +21,239 @@ var (
 	False = &object.Boolean{Value: false}
 )
 
+// DefaultMaxDepth is the maximum call depth used by an Evaluator returned from New.
+const DefaultMaxDepth = 10000
+
+// ErrStepLimit is the cause of the error returned when a program exhausts its step budget.
+var ErrStepLimit = errors.New("step limit exceeded")
+
+// Evaluator walks the AST of a program to evaluate it, tracking the state of the running program.
+type Evaluator struct {
+	// MaxDepth is the maximum number of nested function calls allowed before evaluation is aborted
+	// with an error. A value less than 1 disables the limit.
+	MaxDepth int
+	// MaxSteps is the budget of steps a program may take before evaluation is aborted with an error.
+	// Every function call and loop iteration consumes one step, including the calls builtins such as map
+	// and sort make to functions given to them. A builtin that works without calling functions, such as
+	// repeat or find_all on a long string, takes no steps and runs to completion before the budget is
+	// next checked. A value less than 1 disables the limit.
+	MaxSteps int
+	// Rand is the source of the numbers returned by the random builtin. Setting it to a source with a
+	// fixed seed makes runs reproducible. If nil, a source seeded from the current time is used.
+	Rand *rand.Rand
+	// Out is where the puts and print builtins write. If nil, os.Stdout is used.
+	Out io.Writer
+	// FS is the filesystem the file builtins read and write. If nil, they fail with an IOError, so
+	// scripts can only reach files if the host allows it.
+	FS FS
+	// Clock tells the time builtins the time and waits for the sleep builtin. Setting it to a ManualClock
+	// freezes time, making runs reproducible. If nil, the system clock is used.
+	Clock Clock
+
+	ctx   context.Context
+	steps int
+	stack []object.Frame
+	line  int // Line of the statement being evaluated.
+}
+
+// New returns an Evaluator with the default limits.
+func New() *Evaluator {
+	return &Evaluator{MaxDepth: DefaultMaxDepth}
+}
+
+// Eval evaluates node in env with a new Evaluator using the default limits.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New().Eval(node, env)
+}
+
+// Eval evaluates node in env. The returned Object is an *object.Error if evaluation failed.
+func (e *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
+	return e.EvalContext(context.Background(), node, env)
+}
+
+// EvalContext evaluates node in env, aborting evaluation once ctx is done or the step budget is
+// exhausted. The *object.Error returned when aborted has Err set to ctx.Err() or ErrStepLimit.
+//
+// Both are checked whenever a step is taken, so a builtin call in progress is not interrupted: evaluation
+// is aborted when the builtin returns, or calls a function. The sleep builtin is the exception, returning
+// early once ctx is done.
+//
+// A panic during evaluation is recovered and returned as an InternalError.
+func (e *Evaluator) EvalContext(ctx context.Context, node ast.Node, env *object.Environment) (result object.Object) {
+	e.ctx = ctx
+	e.steps = 0
+	e.stack = e.stack[:0]
+
+	defer func() {
+		if r := recover(); r != nil {
+			result = e.traceError(newInternalError(e.line, r))
+			e.stack = e.stack[:0]
+		}
+	}()
+
+	return e.eval(node, env)
+}
+
+// Random returns the source of random numbers, creating one seeded from the current time if there is none.
+func (e *Evaluator) Random() *rand.Rand {
+	if e.Rand == nil {
+		e.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	return e.Rand
+}
+
+// Output returns the writer the program prints to.
+func (e *Evaluator) Output() io.Writer {
+	if e.Out == nil {
+		return os.Stdout
+	}
+
+	return e.Out
+}
+
+// FileSystem returns the filesystem scripts may use, or nil if the host has not given them one.
+func (e *Evaluator) FileSystem() FS {
+	return e.FS
+}
+
+// TimeSource returns the clock the program tells the time by.
+func (e *Evaluator) TimeSource() Clock {
+	if e.Clock == nil {
+		return systemClock{}
+	}
+
+	return e.Clock
+}
+
+// Context returns the context of the evaluation in progress.
+func (e *Evaluator) Context() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+
+	return e.ctx
+}
+
+// interrupted returns an error if the host has cancelled evaluation or its deadline has passed.
+func (e *Evaluator) interrupted(line int) *object.Error {
+	return contextError(line, e.ctx)
+}
+
+// contextError returns the error aborting evaluation on line if ctx is done, or nil if it is not.
+func contextError(line int, ctx context.Context) *object.Error {
+	err := ctx.Err()
+	if err == nil {
+		return nil
+	}
+
+	errObj := newError(line, object.CancelledError, "execution cancelled")
+	if errors.Is(err, context.DeadlineExceeded) {
+		errObj = newError(line, object.TimeoutError, "execution timed out")
+	}
+
+	errObj.Err = err
+	return errObj
+}
+
+// step consumes one step of the budget, returning an error if evaluation should be aborted.
+func (e *Evaluator) step(line int) *object.Error {
+	if errObj := e.interrupted(line); errObj != nil {
+		return errObj
+	}
+
+	e.steps++
+	if e.MaxSteps > 0 && e.steps > e.MaxSteps {
+		errObj := newError(line, object.StepLimitError, "step limit of %d exceeded", e.MaxSteps)
+		errObj.Err = ErrStepLimit
+		return errObj
+	}
+
+	return nil
+}
+
+func (e *Evaluator) eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
-		return evalProgram(node, env)
+		return e.evalProgram(node, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return e.eval(node.Expression, env)
 	case *ast.NumberLiteral:
 		return &object.Number{Value: node.Value}
 	case *ast.Boolean:
 		return nativeBooltoObject(node.Value)
 	case *ast.PrefixExpression:
-		return evalPrefixExpression(node, env)
+		return e.evalPrefixExpression(node, env)
 	case *ast.InfixExpression:
-		return evalInfixExpression(node, env)
+		return e.evalInfixExpression(node, env)
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return e.evalBlockStatement(node, env)
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env)
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return e.evalIdentifier(node, env)
 	case *ast.FunctionLiteral:
-		return &object.Function{Parameters: node.Parameters, Body: node.Body, Env: env}
+		return &object.Function{Name: node.Name, Parameters: node.Parameters, Body: node.Body, Env: env}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		return e.evalHashLiteral(node, env)
+	case *ast.SetLiteral:
+		return e.evalSetLiteral(node, env)
 	case *ast.ReturnStatement:
-		val := Eval(node.Value, env)
+		val := e.eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.ThrowStatement:
+		return e.evalThrowStatement(node, env)
+	case *ast.TryStatement:
+		return e.evalTryStatement(node, env)
+	case *ast.ForStatement:
+		return e.evalForStatement(node, env)
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		val := e.eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
 		env.Set(node.Name.Value, val)
 		return Null
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		function := e.eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, env)
+		args := e.evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
 
-		return applyFunction(function, args, node.Token.Line)
+		return atCall(e.applyFunction(function, args, node.Token.Line), node.Token.Line, node.Token.Column)
 	case *ast.ArrayLiteral:
-		elements := evalExpressions(node.Elements, env)
+		elements := e.evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
 	case *ast.IndexExpression:
-		return evalIndexExpression(node, env)
+		return e.evalIndexExpression(node, env)
+	case *ast.SliceExpression:
+		return e.evalSliceExpression(node, env)
 	}
 
 	return Null
 }
 
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+func (e *Evaluator) evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range program.Statements {
-		result = Eval(statement, env)
+		e.line = statementLine(statement)
+		result = e.eval(statement, env)
+		if result == nil {
+			continue
+		}
 
 		switch result.Type() {
 		case object.RETURN:
@@ -92,11 +266,12 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	return result
 }
 
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		e.line = statementLine(statement)
+		result = e.eval(statement, env)
 		if result != nil {
 			rt := result.Type()
 			if rt == object.RETURN || rt == object.ERROR {
@@ -108,6 +283,144 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
+func (e *Evaluator) evalThrowStatement(node *ast.ThrowStatement, env *object.Environment) object.Object {
+	val := e.eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	kind, msg := object.UserError, val.Inspect()
+	if hash, ok := val.(*object.Hash); ok {
+		if value, ok := hash.Get(errorMessageKey); ok {
+			msg = value.Inspect()
+		}
+		if value, ok := hash.Get(errorKindKey); ok {
+			kind = object.ErrorKind(value.Inspect())
+		}
+	}
+
+	return &object.Error{Kind: kind, Message: msg, Line: node.Token.Line, Column: node.Token.Column, Value: val}
+}
+
+// evalTryStatement evaluates the try block, handing any error it raises to the catch block. The finally
+// block is always run afterwards, unless evaluation was aborted by the host, and replaces the result if it
+// returns or raises an error itself.
+func (e *Evaluator) evalTryStatement(node *ast.TryStatement, env *object.Environment) object.Object {
+	result := e.eval(node.Body, env)
+
+	errObj, ok := result.(*object.Error)
+	if ok && errObj.Err != nil {
+		return errObj
+	}
+
+	if ok && node.Catch != nil {
+		catchEnv := object.NewEnclodedEnvironment(env)
+		catchEnv.Set(node.Param.Value, errorValue(errObj))
+		result = e.eval(node.Catch, catchEnv)
+	}
+
+	if node.Finally != nil {
+		fin := e.eval(node.Finally, env)
+		if fin != nil && (fin.Type() == object.ERROR || fin.Type() == object.RETURN) {
+			return fin
+		}
+	}
+
+	if result == nil {
+		return Null
+	}
+
+	return result
+}
+
+// evalForStatement runs the body of a for loop once for each item of its iterable. Each item is bound in a
+// scope of its own, so closures made by the body keep the item they were made for.
+func (e *Evaluator) evalForStatement(node *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := e.eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	items, ok := iterate(iterable)
+	if !ok {
+		errObj := newError(node.Token.Line, object.TypeError, "cannot iterate over %s", iterable.Type())
+		errObj.Operands = []object.Object{iterable}
+		return errObj
+	}
+
+	for _, item := range items {
+		if err := e.step(node.Token.Line); err != nil {
+			return err
+		}
+
+		loopEnv := object.NewEnclodedEnvironment(env)
+		loopEnv.Set(node.Variable.Value, item)
+
+		result := e.eval(node.Body, loopEnv)
+		if result != nil && (result.Type() == object.RETURN || result.Type() == object.ERROR) {
+			return result
+		}
+	}
+
+	return Null
+}
+
+// iterate returns the items a for loop visits in obj: the elements of an array or set, the keys of a hash
+// or the characters of a string. It reports false if obj cannot be iterated over.
+func iterate(obj object.Object) ([]object.Object, bool) {
+	switch obj := obj.(type) {
+	case *object.Array:
+		return obj.Elements, true
+	case *object.Set:
+		return obj.Elements(), true
+	case *object.Hash:
+		var keys []object.Object
+		for _, pair := range obj.Pairs() {
+			keys = append(keys, pair.Key)
+		}
+		return keys, true
+	case *object.String:
+		var chars []object.Object
+		for _, ch := range obj.Value {
+			chars = append(chars, &object.String{Value: string(ch)})
+		}
+		return chars, true
+	}
+
+	return nil, false
+}
+
+var (
+	errorKindKey    = &object.String{Value: "kind"}
+	errorMessageKey = &object.String{Value: "message"}
+	errorLineKey    = &object.String{Value: "line"}
+	errorValueKey   = &object.String{Value: "value"}
+)
+
+// errorValue converts a raised error into the value bound by a catch block. Hashes thrown with a message
+// are caught as they are, anything else is described by a hash of the error kind, message, line and the
+// thrown value, if any.
+func errorValue(err *object.Error) object.Object {
+	if hash, ok := err.Value.(*object.Hash); ok {
+		if _, ok := hash.Get(errorMessageKey); ok {
+			return hash
+		}
+	}
+
+	value := object.Object(Null)
+	if err.Value != nil {
+		value = err.Value
+	}
+
+	hash := object.NewHash()
+	hash.Set(errorKindKey, &object.String{Value: string(err.Kind)})
+	hash.Set(errorMessageKey, &object.String{Value: err.Message})
+	hash.Set(errorLineKey, &object.Number{Value: float32(err.Line)})
+	hash.Set(errorValueKey, value)
+
+	return hash
+}
+
 func nativeBooltoObject(input bool) *object.Boolean {
 	if input {
 		return True
@@ -115,8 +428,11 @@ func nativeBooltoObject(input bool) *object.Boolean {
 	return False
 }
 
-func evalPrefixExpression(prefix *ast.PrefixExpression, env *object.Environment) object.Object {
-	right := Eval(prefix.Right, env)
+func (e *Evaluator) evalPrefixExpression(prefix *ast.PrefixExpression, env *object.Environment) object.Object {
+	right := e.eval(prefix.Right, env)
+	if isError(right) {
+		return right
+	}
 
 	switch prefix.Operator {
 	case "!":
@@ -124,7 +440,7 @@ func evalPrefixExpression(prefix *ast.PrefixExpression, env *object.Environment)
 	case "-":
 		return evalMinusPrefixOperatorExpression(prefix, right)
 	default:
-		return newError(prefix.Token.Line, "unknown operator: %s%s", prefix.Operator, right.Type())
+		return newOperatorError(prefix.Token, "unknown operator", prefix.Operator, right)
 	}
 }
 
@@ -137,17 +453,31 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(node *ast.PrefixExpression, right object.Object) object.Object {
+	if d, ok := right.(*object.Duration); ok {
+		return &object.Duration{Value: -d.Value}
+	}
+
 	if right.Type() != object.NUMBER {
-		return newError(node.Token.Line, "unknown operator: -%s", right.Type())
+		return newOperatorError(node.Token, "unknown operator", node.Operator, right)
 	}
 
 	value := right.(*object.Number).Value
 	return &object.Number{Value: -value}
 }
 
-func evalInfixExpression(infix *ast.InfixExpression, env *object.Environment) object.Object {
-	left := Eval(infix.Left, env)
-	right := Eval(infix.Right, env)
+func (e *Evaluator) evalInfixExpression(infix *ast.InfixExpression, env *object.Environment) object.Object {
+	left := e.eval(infix.Left, env)
+	if isError(left) {
+		return left
+	}
+	right := e.eval(infix.Right, env)
+	if isError(right) {
+		return right
+	}
+
+	if infix.Operator == "in" {
+		return evalInExpression(infix, left, right)
+	}
 
 	if left.Type() == object.NUMBER && right.Type() == object.NUMBER {
 		return evalNumberInfixExpression(infix, left, right)
@@ -157,22 +487,90 @@ func evalInfixExpression(infix *ast.InfixExpression, env *object.Environment) ob
 		return evalStringInfixExpression(infix, left, right)
 	}
 
+	if result := evalTimeInfixExpression(infix, left, right); result != nil {
+		return result
+	}
+
 	if left.Type() != right.Type() {
-		return newError(infix.Token.Line, "type mismatch: %s %s %s", left.Type(), infix.Operator, right.Type())
+		return newOperatorError(infix.Token, "type mismatch", infix.Operator, left, right)
+	}
+
+	if left.Type() == object.SET {
+		if result := evalSetInfixExpression(infix, left.(*object.Set), right.(*object.Set)); result != nil {
+			return result
+		}
 	}
 
 	switch infix.Operator {
 	case "==":
-		return nativeBooltoObject(left == right)
+		return nativeBooltoObject(object.Equal(left, right))
 	case "!=":
-		return nativeBooltoObject(left != right)
+		return nativeBooltoObject(!object.Equal(left, right))
 	case "or":
 		return nativeBooltoObject((left == True) || (right == True))
 	case "and":
 		return nativeBooltoObject((left == True) && (right == True))
 	}
 
-	return newError(infix.Token.Line, "unknown operator: %s %s %s", left.Type(), infix.Operator, right.Type())
+	return newOperatorError(infix.Token, "unknown operator", infix.Operator, left, right)
+}
+
+// evalInExpression reports whether left is an element of an array or set, a key of a hash, or a substring
+// of a string.
+func evalInExpression(infix *ast.InfixExpression, left, right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Array:
+		for _, el := range right.Elements {
+			if object.Equal(left, el) {
+				return True
+			}
+		}
+		return False
+	case *object.Set:
+		return nativeBooltoObject(right.Contains(left))
+	case *object.Hash:
+		_, ok := right.Get(left)
+		return nativeBooltoObject(ok)
+	case *object.String:
+		if left, ok := left.(*object.String); ok {
+			return nativeBooltoObject(strings.Contains(right.Value, left.Value))
+		}
+		return newOperatorError(infix.Token, "type mismatch", infix.Operator, left, right)
+	}
+
+	return newOperatorError(infix.Token, "unknown operator", infix.Operator, left, right)
+}
+
+// evalSetInfixExpression evaluates the union, intersection or difference of two sets. It returns nil for
+// other operators, which are evaluated as they are for any other type.
+func evalSetInfixExpression(infix *ast.InfixExpression, left, right *object.Set) object.Object {
+	result := object.NewSet()
+
+	switch infix.Operator {
+	case "|":
+		for _, el := range left.Elements() {
+			result.Add(el)
+		}
+		for _, el := range right.Elements() {
+			result.Add(el)
+		}
+	case "&":
+		for _, el := range left.Elements() {
+			if right.Contains(el) {
+				result.Add(el)
+			}
+		}
+	case "-":
+		for _, el := range left.Elements() {
+			if !right.Contains(el) {
+				result.Add(el)
+			}
+		}
+	default:
+		return nil
+	}
+
+	return result
 }
 
 func evalNumberInfixExpression(infix *ast.InfixExpression, left, right object.Object) object.Object {
@@ -202,7 +600,7 @@ func evalNumberInfixExpression(infix *ast.InfixExpression, left, right object.Ob
 	case "!=":
 		return nativeBooltoObject(leftVal != rightVal)
 	default:
-		return newError(infix.Token.Line, "unknown operator: %s %s %s", left.Type(), infix.Operator, right.Type())
+		return newOperatorError(infix.Token, "unknown operator", infix.Operator, left, right)
 	}
 
 	return &object.Number{Value: result}
@@ -221,19 +619,19 @@ func evalStringInfixExpression(infix *ast.InfixExpression, left, right object.Ob
 		return &object.String{Value: leftVal + rightVal}
 	}
 
-	return newError(infix.Token.Line, "unknown operator: %s %s %s", left.Type(), infix.Operator, right.Type())
+	return newOperatorError(infix.Token, "unknown operator", infix.Operator, left, right)
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+func (e *Evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return e.eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return e.eval(ie.Alternative, env)
 	}
 
 	return Null
@@ -247,9 +645,66 @@ func isTruthy(obj object.Object) bool {
 	return true
 }
 
-func newError(line int, format string, a ...interface{}) *object.Error {
-	msg := fmt.Sprintf(format, a...)
-	return &object.Error{Line: line, Message: fmt.Sprintf("on line %d: %s", line, msg)}
+func newError(line int, kind object.ErrorKind, format string, a ...interface{}) *object.Error {
+	return &object.Error{Kind: kind, Line: line, Message: fmt.Sprintf(format, a...)}
+}
+
+// newOperatorError returns a TypeError for an operator which cannot be applied to its operands, described as
+// reason followed by the operator and operand types as they were written. The error is positioned at tok.
+func newOperatorError(tok token.Token, reason, operator string, operands ...object.Object) *object.Error {
+	var msg string
+	if len(operands) == 1 {
+		msg = fmt.Sprintf("%s: %s%s", reason, operator, operands[0].Type())
+	} else {
+		msg = fmt.Sprintf("%s: %s %s %s", reason, operands[0].Type(), operator, operands[1].Type())
+	}
+
+	return &object.Error{
+		Kind:     object.TypeError,
+		Line:     tok.Line,
+		Column:   tok.Column,
+		Message:  msg,
+		Operator: operator,
+		Operands: operands,
+	}
+}
+
+func newInternalError(line int, recovered interface{}) *object.Error {
+	return newError(line, object.InternalError, "internal error: %v", recovered)
+}
+
+// statementLine returns the line statement starts on.
+func statementLine(statement ast.Statement) int {
+	switch statement := statement.(type) {
+	case *ast.LetStatement:
+		return statement.Token.Line
+	case *ast.ReturnStatement:
+		return statement.Token.Line
+	case *ast.ThrowStatement:
+		return statement.Token.Line
+	case *ast.TryStatement:
+		return statement.Token.Line
+	case *ast.ForStatement:
+		return statement.Token.Line
+	case *ast.ExpressionStatement:
+		return statement.Token.Line
+	case *ast.BlockStatement:
+		return statement.Token.Line
+	}
+
+	return 0
+}
+
+func newHashKeyError(line int, key object.Object) *object.Error {
+	errObj := newError(line, object.TypeError, "unusable as hash key: %s", key.Type())
+	errObj.Operands = []object.Object{key}
+	return errObj
+}
+
+func newSetElementError(line int, el object.Object) *object.Error {
+	errObj := newError(line, object.TypeError, "unusable as set element: %s", el.Type())
+	errObj.Operands = []object.Object{el}
+	return errObj
 }
 
 func isError(obj object.Object) bool {
@@ -260,7 +715,7 @@ func isError(obj object.Object) bool {
 	return false
 }
 
-func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+func (e *Evaluator) evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
 		return val
 	}
@@ -269,14 +724,43 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return builtin
 	}
 
-	return newError(node.Token.Line, "identifier not found: "+node.Value)
+	if constant, ok := constants[node.Value]; ok {
+		return constant
+	}
+
+	errObj := newError(node.Token.Line, object.NameError, "identifier not found: %s", node.Value)
+	errObj.Column = node.Token.Column
+	errObj.Operands = []object.Object{&object.String{Value: node.Value}}
+
+	if s := suggest.Closest(node.Value, knownNames(env)); s != "" {
+		errObj.Message += fmt.Sprintf(", did you mean %s?", s)
+	}
+
+	return errObj
+}
+
+// expressionKeywords are the keywords which, like an identifier, may begin an expression.
+var expressionKeywords = []string{"false", "fn", "if", "true"}
+
+// knownNames returns every name which may be used in env in place of an identifier: its variables, the
+// builtins, the constants and the keywords that begin an expression.
+func knownNames(env *object.Environment) []string {
+	names := env.Names()
+	for name := range builtins {
+		names = append(names, name)
+	}
+	for name := range constants {
+		names = append(names, name)
+	}
+
+	return append(names, expressionKeywords...)
 }
 
-func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+func (e *Evaluator) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
-	for _, e := range exps {
-		ev := Eval(e, env)
+	for _, exp := range exps {
+		ev := e.eval(exp, env)
 		if isError(ev) {
 			return []object.Object{ev}
 		}
@@ -286,19 +770,173 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object, line int) object.Object {
+// applyFunction calls fn with the provided args. Calls in tail position of a function body are returned to
+// here as a TailCall and applied in a loop, so tail recursive functions run in constant stack space and
+// only count once towards the call depth.
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object, line int) object.Object {
+	if fn.Type() != object.FUNCTION {
+		return e.callFunction(fn, args, line)
+	}
+
+	if e.MaxDepth > 0 && len(e.stack) >= e.MaxDepth {
+		return newError(line, object.RecursionError, "maximum recursion depth exceeded")
+	}
+
+	// The frame is not popped in a defer so the stack is intact when a panic is recovered.
+	e.stack = append(e.stack, object.Frame{})
+	result := e.callFunction(fn, args, line)
+	e.stack = e.stack[:len(e.stack)-1]
+
+	return result
+}
+
+// callFunction runs the trampoline for applyFunction, using the frame on top of the call stack for
+// functions.
+func (e *Evaluator) callFunction(fn object.Object, args []object.Object, line int) object.Object {
+	// Column of the tail call being applied, used to position the errors it raises. The errors of the
+	// first call are positioned by the call expression instead.
+	column := 0
+
+	for {
+		if err := e.step(line); err != nil {
+			return err
+		}
+
+		switch fn.Type() {
+		case object.FUNCTION:
+			function := fn.(*object.Function)
+			if len(args) != len(function.Parameters) {
+				errObj := newError(line, object.ArityError, "wrong number of arguments. expected=%d, got=%d", len(function.Parameters), len(args))
+				return atCall(errObj, line, column)
+			}
+			e.stack[len(e.stack)-1] = object.Frame{Function: function.Name, Line: line}
+
+			exEnv := extendFunctionEnv(function, args)
+			evaluated := e.evalTailBlock(function.Body, exEnv, true)
+
+			tc, ok := evaluated.(*object.TailCall)
+			if !ok {
+				return e.traceError(unwrapReturnValue(evaluated))
+			}
+			fn, args, line, column = tc.Function, tc.Arguments, tc.Line, tc.Column
+		case object.BUILTIN:
+			return atCall(e.applyBuiltin(fn.(*object.Builtin), args, line), line, column)
+		default:
+			errObj := newError(line, object.TypeError, "not a function: %s", fn.Type())
+			errObj.Operands = []object.Object{fn}
+			return atCall(errObj, line, column)
+		}
+	}
+}
+
+// atCall positions result at column if it is an error raised on line without a column of its own, as are
+// the errors a call raises itself rather than the function it calls.
+func atCall(result object.Object, line, column int) object.Object {
+	if errObj, ok := result.(*object.Error); ok && errObj.Column == 0 && errObj.Line == line {
+		errObj.Column = column
+	}
+
+	return result
+}
+
+// traceError records the current call stack on obj if it is an error which has not been traced yet.
+func (e *Evaluator) traceError(obj object.Object) object.Object {
+	if errObj, ok := obj.(*object.Error); ok && errObj.Stack == nil {
+		errObj.Stack = append([]object.Frame(nil), e.stack...)
+	}
+
+	return obj
+}
+
+// evalTailBlock evaluates a block within a function body. If tail is true the final statement of the block
+// is in tail position. Return statements are always in tail position.
+func (e *Evaluator) evalTailBlock(block *ast.BlockStatement, env *object.Environment, tail bool) object.Object {
+	var result object.Object
+
+	last := len(block.Statements) - 1
+	for i, statement := range block.Statements {
+		e.line = statementLine(statement)
+		result = e.evalTailStatement(statement, env, tail && i == last)
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN || rt == object.ERROR || rt == object.TAIL_CALL {
+				return result
+			}
+		}
+	}
+
+	return result
+}
+
+func (e *Evaluator) evalTailStatement(statement ast.Statement, env *object.Environment, tail bool) object.Object {
+	switch statement := statement.(type) {
+	case *ast.ReturnStatement:
+		val := e.evalTailExpression(statement.Value, env, true)
+		if isError(val) || (val != nil && val.Type() == object.TAIL_CALL) {
+			return val
+		}
+		return &object.ReturnValue{Value: val}
+	case *ast.ExpressionStatement:
+		return e.evalTailExpression(statement.Expression, env, tail)
+	}
+
+	return e.eval(statement, env)
+}
+
+// evalTailExpression evaluates exp, deferring it as a TailCall if it is a call in tail position. If
+// expressions are always walked as their blocks may contain return statements.
+func (e *Evaluator) evalTailExpression(exp ast.Expression, env *object.Environment, tail bool) object.Object {
+	switch exp := exp.(type) {
+	case *ast.CallExpression:
+		if !tail {
+			break
+		}
+
+		function := e.eval(exp.Function, env)
+		if isError(function) {
+			return function
+		}
+		args := e.evalExpressions(exp.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+
+		return &object.TailCall{Function: function, Arguments: args, Line: exp.Token.Line, Column: exp.Token.Column}
+	case *ast.IfExpression:
+		condition := e.eval(exp.Condition, env)
+		if isError(condition) {
+			return condition
+		}
 
-	switch fn.Type() {
-	case object.FUNCTION:
-		function := fn.(*object.Function)
-		exEnv := extendFunctionEnv(function, args)
-		evaluated := Eval(function.Body, exEnv)
-		return unwrapReturnValue(evaluated)
-	case object.BUILTIN:
-		return fn.(*object.Builtin).Fn(line, args...)
+		if isTruthy(condition) {
+			return e.evalTailBlock(exp.Consequence, env, tail)
+		} else if exp.Alternative != nil {
+			return e.evalTailBlock(exp.Alternative, env, tail)
+		}
+
+		return Null
 	}
 
-	return newError(line, "not a function: %s", fn.Type())
+	return e.eval(exp, env)
+}
+
+// Call calls fn with args as if it was called on line, so that builtins can call the functions passed to them.
+func (e *Evaluator) Call(line int, fn object.Object, args ...object.Object) object.Object {
+	return e.applyFunction(fn, args, line)
+}
+
+// applyBuiltin calls builtin with args, converting a panic within the builtin into an InternalError.
+func (e *Evaluator) applyBuiltin(builtin *object.Builtin, args []object.Object, line int) (result object.Object) {
+	depth := len(e.stack)
+
+	defer func() {
+		if r := recover(); r != nil {
+			result = newInternalError(line, r)
+			e.stack = e.stack[:depth]
+		}
+	}()
+
+	return builtin.Fn(e, line, args...)
 }
 
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
@@ -312,6 +950,10 @@ func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Enviro
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
+	if obj == nil {
+		return Null
+	}
+
 	if obj.Type() == object.RETURN {
 		return obj.(*object.ReturnValue).Value
 	}
@@ -319,12 +961,12 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	return obj
 }
 
-func evalIndexExpression(node *ast.IndexExpression, env *object.Environment) object.Object {
-	left := Eval(node.Left, env)
+func (e *Evaluator) evalIndexExpression(node *ast.IndexExpression, env *object.Environment) object.Object {
+	left := e.eval(node.Left, env)
 	if isError(left) {
 		return left
 	}
-	index := Eval(node.Index, env)
+	index := e.eval(node.Index, env)
 	if isError(index) {
 		return index
 	}
@@ -332,11 +974,16 @@ func evalIndexExpression(node *ast.IndexExpression, env *object.Environment) obj
 	switch {
 	case left.Type() == object.ARRAY && index.Type() == object.NUMBER:
 		return evalArrayIndexExpression(left.(*object.Array), index.(*object.Number))
+	case left.Type() == object.STRING && index.Type() == object.NUMBER:
+		return evalStringIndexExpression(left.(*object.String), index.(*object.Number))
 	case left.Type() == object.HASH:
 		return evalHashIndexExpression(node.Token.Line, left.(*object.Hash), index)
 	}
 
-	return newError(node.Token.Line, "index operator not supported: %s", left.Type())
+	errObj := newError(node.Token.Line, object.TypeError, "index operator not supported: %s", left.Type())
+	errObj.Operator = "[]"
+	errObj.Operands = []object.Object{left, index}
+	return errObj
 }
 
 func evalArrayIndexExpression(array *object.Array, index *object.Number) object.Object {
@@ -350,42 +997,164 @@ func evalArrayIndexExpression(array *object.Array, index *object.Number) object.
 	return array.Elements[idx]
 }
 
-func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+// evalStringIndexExpression returns the character of str at index, counting in characters rather than bytes.
+func evalStringIndexExpression(str *object.String, index *object.Number) object.Object {
+	chars := []rune(str.Value)
+	idx := int(index.Value)
+
+	if idx < 0 || idx > len(chars)-1 {
+		return Null
+	}
+
+	return &object.String{Value: string(chars[idx])}
+}
+
+// evalSliceExpression returns the elements of an array, or the characters of a string, selected by a slice.
+// As in Python, negative bounds count back from the end, bounds past either end are clamped to it, and a
+// negative step walks backwards.
+func (e *Evaluator) evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := e.eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	var bounds [3]*int
+	for i, exp := range []ast.Expression{node.Start, node.End, node.Step} {
+		if exp == nil {
+			continue
+		}
+
+		bound := e.eval(exp, env)
+		if isError(bound) {
+			return bound
+		}
+
+		num, ok := bound.(*object.Number)
+		if !ok {
+			errObj := newError(node.Token.Line, object.TypeError, "slice bounds must be NUMBER, got=%s", bound.Type())
+			errObj.Operands = []object.Object{bound}
+			return errObj
+		}
+
+		value := int(num.Value)
+		bounds[i] = &value
+	}
+
+	if bounds[2] != nil && *bounds[2] == 0 {
+		return newError(node.Token.Line, object.ValueError, "slice step cannot be zero")
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		var elements []object.Object
+		for _, i := range sliceIndexes(len(left.Elements), bounds[0], bounds[1], bounds[2]) {
+			elements = append(elements, left.Elements[i])
+		}
+		return &object.Array{Elements: elements}
+	case *object.String:
+		chars := []rune(left.Value)
+
+		var out []rune
+		for _, i := range sliceIndexes(len(chars), bounds[0], bounds[1], bounds[2]) {
+			out = append(out, chars[i])
+		}
+		return &object.String{Value: string(out)}
+	}
+
+	errObj := newError(node.Token.Line, object.TypeError, "slice operator not supported: %s", left.Type())
+	errObj.Operator = "[:]"
+	errObj.Operands = []object.Object{left}
+	return errObj
+}
+
+// sliceIndexes returns the indexes, into a sequence of length n, selected by a slice. Bounds left out of the
+// slice are nil, and step must not be zero.
+func sliceIndexes(n int, start, end, step *int) []int {
+	inc := 1
+	if step != nil {
+		inc = *step
+	}
 
-	for key, value := range node.Pairs {
-		k := Eval(key, env)
+	// lo and hi are the range start and end are clamped to, and so also their defaults. Walking backwards
+	// the end must be able to fall before the first element, so the range is shifted down by one.
+	lo, hi := 0, n
+	if inc < 0 {
+		lo, hi = -1, n-1
+	}
+
+	bound := func(b *int, def int) int {
+		if b == nil {
+			return def
+		}
+
+		i := *b
+		if i < 0 {
+			i += n
+		}
+		return max(lo, min(i, hi))
+	}
+
+	first, last := bound(start, lo), bound(end, hi)
+	if inc < 0 {
+		first, last = bound(start, hi), bound(end, lo)
+	}
+
+	var indexes []int
+	for i := first; (inc > 0 && i < last) || (inc < 0 && i > last); i += inc {
+		indexes = append(indexes, i)
+	}
+
+	return indexes
+}
+
+func (e *Evaluator) evalSetLiteral(node *ast.SetLiteral, env *object.Environment) object.Object {
+	elements := e.evalExpressions(node.Elements, env)
+	if len(elements) == 1 && isError(elements[0]) {
+		return elements[0]
+	}
+
+	for _, el := range elements {
+		if _, ok := el.(object.Hashable); !ok {
+			return newSetElementError(node.Token.Line, el)
+		}
+	}
+
+	return object.NewSet(elements...)
+}
+
+func (e *Evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for _, pair := range node.Pairs {
+		k := e.eval(pair.Key, env)
 		if isError(k) {
 			return k
 		}
 
-		hk, ok := k.(object.Hashable)
-		if !ok {
-			return newError(node.Token.Line, "unusable as hash key: %s", k.Type())
+		if _, ok := k.(object.Hashable); !ok {
+			return newHashKeyError(node.Token.Line, k)
 		}
 
-		v := Eval(value, env)
+		v := e.eval(pair.Value, env)
 		if isError(v) {
 			return v
 		}
 
-		hashed := hk.HashKey()
-		pairs[hashed] = object.HashPair{Key: k, Value: v}
+		hash.Set(k, v)
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalHashIndexExpression(line int, hash *object.Hash, index object.Object) object.Object {
-	key, ok := index.(object.Hashable)
-	if !ok {
-		return newError(line, "unusable as hash key: %s", index.Type())
+	if _, ok := index.(object.Hashable); !ok {
+		return newHashKeyError(line, index)
 	}
 
-	pair, ok := hash.Pairs[key.HashKey()]
+	value, ok := hash.Get(index)
 	if !ok {
 		return Null
 	}
 
-	return pair.Value
+	return value
 }
@@ -1,11 +1,16 @@
 package evaluator
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/butlermatt/monlox/ast"
 	"github.com/butlermatt/monlox/lexer"
 	"github.com/butlermatt/monlox/object"
 	"github.com/butlermatt/monlox/parser"
+	"github.com/butlermatt/monlox/token"
 )
 
 func TestEvalNumberExpression(t *testing.T) {
@@ -73,6 +78,19 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{`"hello" == "world"`, false},
 		{`"hello" != "hello"`, false},
 		{`"hello" != "world"`, true},
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, 2] == [1, 2, 3]", false},
+		{`[[1, "a"], []] == [[1, "a"], []]`, true},
+		{`[[1, "a"], []] == [[1, "b"], []]`, false},
+		{`[1, true] == [1, "true"]`, false},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"b": 1}`, false},
+		{`{"a": 1} != {"a": 1, "b": 2}`, true},
+		{"let f = fn() { 1 }; f == f", true},
+		{"fn() { 1 } == fn() { 1 }", false},
 	}
 
 	for _, tt := range tests {
@@ -147,25 +165,36 @@ func TestReturnStatements(t *testing.T) {
 func TestErrorHandling(t *testing.T) {
 	tests := []struct {
 		input    string
+		kind     object.ErrorKind
 		expected string
+		line     int
 	}{
-		{"5 + true;", "on line 1: type mismatch: NUMBER + BOOLEAN"},
-		{"5 + true; 5;", "on line 1: type mismatch: NUMBER + BOOLEAN"},
-		{"-true;", "on line 1: unknown operator: -BOOLEAN"},
-		{"true + false;", "on line 1: unknown operator: BOOLEAN + BOOLEAN"},
-		{"5; true + false; 5", "on line 1: unknown operator: BOOLEAN + BOOLEAN"},
-		{"if (10 > 1) { true + false; }", "on line 1: unknown operator: BOOLEAN + BOOLEAN"},
-		{"if (1 == true) { 10 }", "on line 1: type mismatch: NUMBER == BOOLEAN"},
+		{"5 + true;", object.TypeError, "type mismatch: NUMBER + BOOLEAN", 1},
+		{"5 + true; 5;", object.TypeError, "type mismatch: NUMBER + BOOLEAN", 1},
+		{"-true;", object.TypeError, "unknown operator: -BOOLEAN", 1},
+		{"true + false;", object.TypeError, "unknown operator: BOOLEAN + BOOLEAN", 1},
+		{"5; true + false; 5", object.TypeError, "unknown operator: BOOLEAN + BOOLEAN", 1},
+		{"if (10 > 1) { true + false; }", object.TypeError, "unknown operator: BOOLEAN + BOOLEAN", 1},
+		{"if (1 == true) { 10 }", object.TypeError, "type mismatch: NUMBER == BOOLEAN", 1},
 		{`
 if (10 > 1) {
    if (10 > 1) { 
      return true + false 
    } 
    return 1; 
-}`, "on line 4: unknown operator: BOOLEAN + BOOLEAN"},
-		{"foobar", "on line 1: identifier not found: foobar"},
-		{`"Hello" - "World"`, "on line 1: unknown operator: STRING - STRING"},
-		{`{"name": "Monkey"}[fn(x) { x }];`, "on line 1: unusable as hash key: FUNCTION"},
+}`, object.TypeError, "unknown operator: BOOLEAN + BOOLEAN", 4},
+		{"foobar", object.NameError, "identifier not found: foobar", 1},
+		{"lenn([1])", object.NameError, "identifier not found: lenn, did you mean len?", 1},
+		{"let counter = 1; let f = fn(x) { countr + x }; f(1)", object.NameError, "identifier not found: countr, did you mean counter?", 1},
+		{"let f = fn(value) { fn() { valeu } }; f(1)()", object.NameError, "identifier not found: valeu, did you mean value?", 1},
+		{"tru", object.NameError, "identifier not found: tru, did you mean true?", 1},
+		{"let x = thorw; x", object.NameError, "identifier not found: thorw", 1},
+		{`"Hello" - "World"`, object.TypeError, "unknown operator: STRING - STRING", 1},
+		{`{"name": "Monkey"}[fn(x) { x }];`, object.TypeError, "unusable as hash key: FUNCTION", 1},
+		{"5();", object.TypeError, "not a function: NUMBER", 1},
+		{"5[0];", object.TypeError, "index operator not supported: NUMBER", 1},
+		{"fn(x, y) { x }(1);", object.ArityError, "wrong number of arguments. expected=2, got=1", 1},
+		{"fn(x) { x }(1, 2);", object.ArityError, "wrong number of arguments. expected=1, got=2", 1},
 	}
 
 	for i, tt := range tests {
@@ -177,12 +206,158 @@ if (10 > 1) {
 			continue
 		}
 
+		if errObj.Kind != tt.kind {
+			t.Errorf("test %d: wrong error kind. expected=%q, got=%q", i+1, tt.kind, errObj.Kind)
+		}
+
 		if errObj.Message != tt.expected {
 			t.Errorf("test %d: wrong error message. expected=%q, got=%q", i+1, tt.expected, errObj.Message)
 		}
+
+		if errObj.Line != tt.line {
+			t.Errorf("test %d: wrong error line. expected=%d, got=%d", i+1, tt.line, errObj.Line)
+		}
+	}
+}
+
+func TestErrorOperands(t *testing.T) {
+	evaluated := testEval(`5 + "five"`)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("wrong type returned. expected=*object.Error, got=%T (%+[1]v)", evaluated)
+	}
+
+	if errObj.Operator != "+" {
+		t.Errorf("wrong operator. expected=%q, got=%q", "+", errObj.Operator)
+	}
+
+	if len(errObj.Operands) != 2 {
+		t.Fatalf("wrong number of operands. expected=%d, got=%d", 2, len(errObj.Operands))
+	}
+
+	testNumberObject(t, errObj.Operands[0], 5)
+	if str, ok := errObj.Operands[1].(*object.String); !ok || str.Value != "five" {
+		t.Errorf("wrong right operand. expected=%q, got=%+v", "five", errObj.Operands[1])
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } catch (e) { 2 }`, float32(1)},
+		{`try { throw "boom"; 1 } catch (e) { 2 }`, float32(2)},
+		{`try { throw "boom" } catch (e) { e["message"] }`, "boom"},
+		{`try { throw "boom" } catch (e) { e["kind"] }`, "Error"},
+		{`try { throw 5 } catch (e) { e["value"] }`, float32(5)},
+		{`try { throw {"kind": "ValueError", "message": "bad"} } catch (e) { e["kind"] }`, "ValueError"},
+		{`try { 1 + true } catch (e) { e["kind"] }`, "TypeError"},
+		{`try { 1 + true } catch (e) { e["message"] }`, "type mismatch: NUMBER + BOOLEAN"},
+		{`try { foo } catch (e) { e["kind"] }`, "NameError"},
+		{`let f = fn(x) { x }; try { f() } catch (e) { e["kind"] }`, "ArityError"},
+		{`throw {"kind": "ValueError", "message": "bad"}`, "ValueError line 1: bad"},
+		{"try {\n\n 1 + true } catch (e) { e[\"line\"] }", float32(3)},
+		{`let f = fn() { throw "inner" }; try { f() } catch (e) { e["message"] }`, "inner"},
+		{`try { try { throw "a" } catch (e) { throw e["message"] + "b" } } catch (e) { e["message"] }`, "ab"},
+		{`try { try { throw "a" } catch (e) { throw e } } catch (e) { e["kind"] }`, "Error"},
+		{`let x = 1; try { let x = 2; } finally { let x = x + 10; }; x`, float32(12)},
+		{`let x = 1; try { throw "a" } catch (e) { let x = 2; } finally { let x = x * 3; }; x`, float32(3)},
+		{`let f = fn() { try { return 1; } finally { 2 } }; f()`, float32(1)},
+		{`let f = fn() { try { return 1; } finally { return 2; } }; f()`, float32(2)},
+		{`try { throw "a" } finally { 1 }`, "Error line 1: a"},
+		{`try { 1 } catch (e) { 2 } finally { throw "fin" }`, "Error line 1: fin"},
+		{`throw "boom"`, "Error line 1: boom"},
+		{`throw {"message": "boom"}`, `Error line 1: boom`},
+	}
+
+	for i, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case float32:
+			testNumberObject(t, evaluated, expected)
+		case string:
+			if evaluated.Inspect() != expected {
+				t.Errorf("test %d: wrong result. expected=%q, got=%q", i, expected, evaluated.Inspect())
+			}
+		}
 	}
 }
 
+func TestTryCatchAbortedEvaluation(t *testing.T) {
+	input := "let loop = fn() { loop() }; try { loop() } catch (e) { 1 } finally { 2 }"
+
+	e := New()
+	e.MaxSteps = 10
+	evaluated := e.Eval(parser.New(lexer.New(input)).ParseProgram(), object.NewEnvironment())
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is unexpected type. expected=*object.Error, got=%T (%+[1]v)", evaluated)
+	}
+
+	if errObj.Err != ErrStepLimit {
+		t.Errorf("wrong error cause. expected=%v, got=%v", ErrStepLimit, errObj.Err)
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	// The panicking builtin is bound in the environment of this test alone, leaving the builtins shared by
+	// every test as they are.
+	env := object.NewEnvironment()
+	env.Set("explode", &object.Builtin{
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			return args[5]
+		},
+	})
+
+	input := `let f = fn() {
+  explode()
+};
+try { f() } catch (e) { e["kind"] }`
+
+	evaluated := Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	if evaluated.Inspect() != string(object.InternalError) {
+		t.Errorf("builtin panic not caught as InternalError. got=%s", evaluated.Inspect())
+	}
+
+	// A let statement missing its name, as produced by a failed parse.
+	program := &ast.Program{Statements: []ast.Statement{
+		&ast.ExpressionStatement{Token: token.New(token.NUM, "1", 1), Expression: &ast.NumberLiteral{Value: 1}},
+		&ast.LetStatement{Token: token.New(token.LET, "let", 2), Value: &ast.NumberLiteral{Value: 2}},
+	}}
+
+	e := New()
+	evaluated = e.Eval(program, object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is unexpected type. expected=*object.Error, got=%T (%+[1]v)", evaluated)
+	}
+
+	if errObj.Kind != object.InternalError {
+		t.Errorf("wrong error kind. expected=%q, got=%q", object.InternalError, errObj.Kind)
+	}
+
+	if errObj.Line != 2 {
+		t.Errorf("wrong error line. expected=%d, got=%d", 2, errObj.Line)
+	}
+
+	// A for loop whose iterable is missing, as produced by a failed parse.
+	program = &ast.Program{Statements: []ast.Statement{
+		&ast.ExpressionStatement{Token: token.New(token.NUM, "1", 1), Expression: &ast.NumberLiteral{Value: 1}},
+		&ast.ForStatement{Token: token.New(token.FOR, "for", 3), Iterable: (*ast.Identifier)(nil)},
+	}}
+
+	evaluated = e.Eval(program, object.NewEnvironment())
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Line != 3 {
+		t.Errorf("wrong error for a panic in a for statement. expected an InternalError on line 3, got=%s", evaluated.Inspect())
+	}
+
+	testNumberObject(t, e.Eval(parser.New(lexer.New("fn() {}(); 3")).ParseProgram(), object.NewEnvironment()), 3)
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -254,6 +429,166 @@ addTwo(2);`
 	testNumberObject(t, testEval(input), 4)
 }
 
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float32
+	}{
+		{"let count = fn(n) { if (n == 0) { return 0; } count(n - 1) }; count(100000);", 0},
+		{"let count = fn(n) { if (n == 0) { 0 } else { count(n - 1) } }; count(100000);", 0},
+		{"let count = fn(n) { if (n > 0) { return count(n - 1); } 7 }; count(100000);", 7},
+		{"let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; if (even(100001)) { 1 } else { 2 }", 2},
+		{"let sum = fn(n, acc) { if (n == 0) { return acc; } return sum(n - 1, acc + n); }; sum(100, 0);", 5050},
+		{"let fact = fn(n) { if (n == 0) { return 1; } n * fact(n - 1) }; fact(5);", 120},
+		{"let f = fn(n) { if (n > 0) { f(n - 1); } n }; f(3);", 3},
+		{"let f = fn() { len([1, 2]) }; f();", 2},
+	}
+
+	for _, tt := range tests {
+		testNumberObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestRecursionDepth(t *testing.T) {
+	tests := []struct {
+		input    string
+		maxDepth int
+		expected interface{}
+	}{
+		{"let f = fn(n) { 1 + f(n) }; f(1);", DefaultMaxDepth, "maximum recursion depth exceeded"},
+		{"let f = fn(n) { if (n == 0) { return 0; } 1 + f(n - 1) }; f(5);", 6, float32(5)},
+		{"let f = fn(n) { if (n == 0) { return 0; } 1 + f(n - 1) }; f(5);", 5, "maximum recursion depth exceeded"},
+		{"let f = fn(n) { if (n == 0) { return 0; } f(n - 1) }; f(50);", 2, float32(0)},
+		{"let f = fn(n) { if (n == 0) { return 0; } 1 + f(n - 1) }; f(50);", 0, float32(50)},
+	}
+
+	for i, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		e := New()
+		e.MaxDepth = tt.maxDepth
+
+		evaluated := e.Eval(program, object.NewEnvironment())
+		switch expected := tt.expected.(type) {
+		case float32:
+			testNumberObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("test %d: object is unexpected type. expected=*object.Error, got=%T (%+[2]v)", i, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("test %d: wrong error message. expected=%q, got=%q", i, expected, errObj.Message)
+			}
+		}
+
+		if len(e.stack) != 0 {
+			t.Errorf("test %d: call stack not unwound. got=%d", i, len(e.stack))
+		}
+	}
+}
+
+func TestExecutionBudget(t *testing.T) {
+	loop := "let loop = fn(n) { loop(n + 1) }; loop(0);"
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelTimeout()
+
+	tests := []struct {
+		ctx      context.Context
+		maxSteps int
+		message  string
+		cause    error
+	}{
+		{context.Background(), 100, "step limit of 100 exceeded", ErrStepLimit},
+		{cancelled, 0, "execution cancelled", context.Canceled},
+		{timeout, 0, "execution timed out", context.DeadlineExceeded},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(loop)).ParseProgram()
+		e := New()
+		e.MaxSteps = tt.maxSteps
+
+		evaluated := e.EvalContext(tt.ctx, program, object.NewEnvironment())
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("test %d: object is unexpected type. expected=*object.Error, got=%T (%+[2]v)", i, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.message {
+			t.Errorf("test %d: wrong error message. expected=%q, got=%q", i, tt.message, errObj.Message)
+		}
+
+		if !errors.Is(errObj.Err, tt.cause) {
+			t.Errorf("test %d: wrong error cause. expected=%v, got=%v", i, tt.cause, errObj.Err)
+		}
+	}
+
+	e := New()
+	e.MaxSteps = 3
+	program := parser.New(lexer.New("let f = fn(x) { x }; f(1); f(2); f(3);")).ParseProgram()
+	testNumberObject(t, e.Eval(program, object.NewEnvironment()), 3)
+
+	e = New()
+	e.MaxSteps = 3
+	program = parser.New(lexer.New("for (x in [1, 2, 3, 4]) { x }")).ParseProgram()
+	if errObj, ok := e.Eval(program, object.NewEnvironment()).(*object.Error); !ok || errObj.Kind != object.StepLimitError {
+		t.Errorf("loop iterations not counted as steps. got=%v", errObj)
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	input := `let inner = fn(x) {
+  x + true
+};
+let outer = fn(x) {
+  let y = inner(x);
+  y
+};
+let anon = fn(f) { let r = f(1); r };
+anon(outer);`
+
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is unexpected type. expected=*object.Error, got=%T (%+[1]v)", evaluated)
+	}
+
+	expected := []object.Frame{
+		{Function: "anon", Line: 9},
+		{Function: "outer", Line: 8},
+		{Function: "inner", Line: 5},
+	}
+
+	if len(errObj.Stack) != len(expected) {
+		t.Fatalf("stack has wrong number of frames. expected=%d, got=%d (%+v)", len(expected), len(errObj.Stack), errObj.Stack)
+	}
+
+	for i, frame := range expected {
+		if errObj.Stack[i] != frame {
+			t.Errorf("stack frame %d wrong. expected=%+v, got=%+v", i, frame, errObj.Stack[i])
+		}
+	}
+
+	traceback := `Traceback (most recent call last):
+  line 9, in <program>
+  line 8, in anon
+  line 5, in outer
+  line 2, in inner
+TypeError line 2: type mismatch: NUMBER + BOOLEAN`
+
+	if errObj.Traceback() != traceback {
+		t.Errorf("traceback wrong.\nexpected=%s\ngot=%s", traceback, errObj.Traceback())
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello World!"`
 
@@ -290,21 +625,31 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, float32(0)},
 		{`len("four")`, float32(4)},
 		{`len("hello world")`, float32(11)},
-		{`len(1)`, "on line 1: argument to `len` not supported. got=NUMBER"},
-		{`len("one", "two")`, "on line 1: wrong number of arguments. expected=1, got=2"},
+		{`len("héllo")`, float32(5)},
+		{`len(1)`, "argument to `len` not supported. got=NUMBER"},
+		{`len("one", "two")`, "wrong number of arguments. expected=1, got=2"},
 		{`len([1, 2, 3])`, float32(3)},
 		{`len([])`, float32(0)},
 		{`first([1, 2, 3])`, float32(1)},
 		{`first([])`, nil},
-		{`first(1)`, "on line 1: argument to `first` must be ARRAY, got=NUMBER"},
+		{`first(1)`, "argument to `first` must be ARRAY, got=NUMBER"},
 		{`last([1, 2, 3])`, float32(3)},
 		{`last([])`, nil},
-		{`last(1)`, "on line 1: argument to `last` must be ARRAY, got=NUMBER"},
+		{`last(1)`, "argument to `last` must be ARRAY, got=NUMBER"},
 		{`rest([1, 2, 3])`, []float32{2, 3}},
 		{`rest([])`, nil},
 		{`push([], 1)`, []float32{1}},
-		{`push(1, 1)`, "on line 1: first argument to `push` must be ARRAY, got=NUMBER"},
+		{`push(1, 1)`, "first argument to `push` must be ARRAY, got=NUMBER"},
 		{`puts("hello", "world!")`, nil},
+		{`len(#{1, 2, 2})`, float32(2)},
+		{`add(#{1}, [2])`, "#{1, [2]}"},
+		{`add(#{1}, 1)`, "#{1}"},
+		{`add([1], 2)`, "first argument to `add` must be SET, got=ARRAY"},
+		{`add(#{1}, fn() {})`, "unusable as set element: FUNCTION"},
+		{`remove(#{1, 2, 3}, 2)`, "#{1, 3}"},
+		{`remove(#{1}, 2)`, "#{1}"},
+		{`let s = #{1}; add(s, 2); s`, "#{1}"},
+		{`remove(1, 1)`, "first argument to `remove` must be SET, got=NUMBER"},
 	}
 
 	for i, tt := range tests {
@@ -316,6 +661,13 @@ func TestBuiltinFunctions(t *testing.T) {
 		case float32:
 			testNumberObject(t, evaluated, float32(expected))
 		case string:
+			if set, ok := evaluated.(*object.Set); ok {
+				if set.Inspect() != expected {
+					t.Errorf("test %d: wrong set. expected=%q, got=%q", i, expected, set.Inspect())
+				}
+				continue
+			}
+
 			errObj, ok := evaluated.(*object.Error)
 			if !ok {
 				t.Errorf("test %d: object is unexpected type. expected=*object.Error, got=%T (%+[2]v", i, evaluated)
@@ -324,7 +676,7 @@ func TestBuiltinFunctions(t *testing.T) {
 			if errObj.Message != expected {
 				t.Errorf("unexpected error message. expected=%q, got=%q", expected, errObj.Message)
 			}
-		case []int:
+		case []float32:
 			array, ok := evaluated.(*object.Array)
 			if !ok {
 				t.Errorf("obj not Array. got=%T (%+v)", evaluated, evaluated)
@@ -338,7 +690,7 @@ func TestBuiltinFunctions(t *testing.T) {
 			}
 
 			for i, expectedElem := range expected {
-				testNumberObject(t, array.Elements[i], float32(expectedElem))
+				testNumberObject(t, array.Elements[i], expectedElem)
 			}
 		}
 	}
@@ -390,6 +742,175 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestSets(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"#{}", "#{}"},
+		{"#{3, 1, 2, 1}", "#{3, 1, 2}"},
+		{`#{[1, 2], [1, 2], {"a": 1}, #{1}}`, "#{[1, 2], {a: 1}, #{1}}"},
+		{"#{1, 2} | #{2, 3}", "#{1, 2, 3}"},
+		{"#{1, 2, 3} & #{3, 2, 4}", "#{2, 3}"},
+		{"#{1, 2, 3} - #{2}", "#{1, 3}"},
+		{"#{1} == #{1}", "true"},
+		{"#{1, 2} == #{2, 1}", "true"},
+		{"#{1, 2} != #{1}", "true"},
+		{"{#{1, 2}: 5}[#{2, 1}]", "5"},
+		{"#{fn() {}}", "TypeError line 1: unusable as set element: FUNCTION"},
+		{"#{1} * #{2}", "TypeError line 1: unknown operator: SET * SET"},
+		{"#{1} | [2]", "TypeError line 1: type mismatch: SET | ARRAY"},
+		{"1 | 2", "TypeError line 1: unknown operator: NUMBER | NUMBER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestInOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1 in #{1, 2}", true},
+		{"3 in #{1, 2}", false},
+		{"[1] in #{[1]}", true},
+		{"2 in [1, 2]", true},
+		{"[2] in [[1], [2]]", true},
+		{"3 in [1, 2]", false},
+		{`"a" in {"a": 1}`, true},
+		{`1 in {"a": 1}`, false},
+		{`"ell" in "hello"`, true},
+		{`"" in "hello"`, true},
+		{`"z" in "hello"`, false},
+		{"1 in #{1} == true", true},
+		{`1 in "1"`, "type mismatch: NUMBER in STRING"},
+		{"1 in 1", "unknown operator: NUMBER in NUMBER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is unexpected type. expected=*object.Error, got=%T (%+[1]v)", evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let find = fn(xs) { for (x in xs) { if (x > 2) { return x } } -1 }; find([1, 2, 3, 4])", "3"},
+		{"let find = fn(xs) { for (x in xs) { if (x > 2) { return x } } -1 }; find([1, 2])", "-1"},
+		{"let first = fn(xs) { for (x in xs) { return x } }; first(#{5, 6})", "5"},
+		{`let f = fn(h) { for (k in h) { return k } }; f({"b": 1, "a": 2})`, "b"},
+		{`let f = fn(s) { for (c in s) { if (c != "h") { return c } } }; f("héllo")`, "é"},
+		{"let f = fn() { for (x in [7]) { return fn() { x } } }; f()()", "7"},
+		{"for (x in []) { x }", "null"},
+		{"for (x in [1]) { x }; x", "NameError line 1: identifier not found: x"},
+		{"for (x in [1, 2]) { if (x == 2) { throw \"two\" } }", "Error line 1: two"},
+		{"for (x in 5) { x }", "TypeError line 1: cannot iterate over NUMBER"},
+		{"for (x in y) { x }", "NameError line 1: identifier not found: y"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"abc"[0]`, "a"},
+		{`"abc"[2]`, "c"},
+		{`"héllo"[1]`, "é"},
+		{`"héllo"[2]`, "l"},
+		{`"abc"[3]`, nil},
+		{`"abc"[-1]`, nil},
+		{`""[0]`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		expected, ok := tt.expected.(string)
+		if !ok {
+			testNullObject(t, evaluated)
+			continue
+		}
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+[1]v)", evaluated)
+			continue
+		}
+		if str.Value != expected {
+			t.Errorf("wrong character for %s. expected=%q, got=%q", tt.input, expected, str.Value)
+		}
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3, 4, 5][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4, 5][:2]", "[1, 2]"},
+		{"[1, 2, 3, 4, 5][3:]", "[4, 5]"},
+		{"[1, 2, 3, 4, 5][:]", "[1, 2, 3, 4, 5]"},
+		{"[1, 2, 3, 4, 5][-2:]", "[4, 5]"},
+		{"[1, 2, 3, 4, 5][:-2]", "[1, 2, 3]"},
+		{"[1, 2, 3, 4, 5][-10:10]", "[1, 2, 3, 4, 5]"},
+		{"[1, 2, 3, 4, 5][3:1]", "[]"},
+		{"[1, 2, 3, 4, 5][::2]", "[1, 3, 5]"},
+		{"[1, 2, 3, 4, 5][1::2]", "[2, 4]"},
+		{"[1, 2, 3, 4, 5][::-1]", "[5, 4, 3, 2, 1]"},
+		{"[1, 2, 3, 4, 5][3:0:-1]", "[4, 3, 2]"},
+		{"[1, 2, 3, 4, 5][-1:-4:-2]", "[5, 3]"},
+		{"[1, 2, 3, 4, 5][10::-2]", "[5, 3, 1]"},
+		{"[1, 2, 3, 4, 5][:-10:-1]", "[5, 4, 3, 2, 1]"},
+		{"[][::-1]", "[]"},
+		{"let a = [1, 2, 3]; let i = 1; a[i:i + 1]", "[2]"},
+		{`"hello"[1:4]`, "ell"},
+		{`"hello"[::-1]`, "olleh"},
+		{`"héllo"[:2]`, "hé"},
+		{`"héllo"[-4:]`, "éllo"},
+		{"[1, 2][::0]", "ValueError line 1: slice step cannot be zero"},
+		{`[1, 2]["a":]`, "TypeError line 1: slice bounds must be NUMBER, got=STRING"},
+		{`{"a": 1}[0:1]`, "TypeError line 1: slice operator not supported: HASH"},
+		{"[1, 2][x:]", "NameError line 1: identifier not found: x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
 {
@@ -407,26 +928,53 @@ func TestHashLiterals(t *testing.T) {
 		t.Fatalf("eval returned wrong type. expected=*object.Hash, got=%T (%+[1]v)", evaluated)
 	}
 
-	expected := map[object.HashKey]float32{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.Number{Value: 4}).HashKey():       4,
-		True.HashKey():                             5,
-		False.HashKey():                            6,
+	expected := []struct {
+		key   object.Object
+		value float32
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Number{Value: 4}, 4},
+		{True, 5},
+		{False, 6},
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong number of elements. expected=%d, got=%d", len(expected), len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong number of elements. expected=%d, got=%d", len(expected), result.Len())
 	}
 
-	for exKey, exVal := range expected {
-		p, ok := result.Pairs[exKey]
+	for _, ex := range expected {
+		value, ok := result.Get(ex.key)
 		if !ok {
-			t.Errorf("no pair for given key %q", exKey)
+			t.Errorf("no pair for given key %s", ex.key.Inspect())
+			continue
 		}
 
-		testNumberObject(t, p.Value, exVal)
+		testNumberObject(t, value, ex.value)
+	}
+}
+
+func TestHashOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, "c": 3}`, "{b: 1, a: 2, c: 3}"},
+		{`{3: "c", 1: "a", 2: "b"}`, "{3: c, 1: a, 2: b}"},
+		{`{"b": 1, "a": 2, "b": 3}`, "{b: 3, a: 2}"},
+		{`{"z": {"y": 1, "x": 2}, "w": [1]}`, "{z: {y: 1, x: 2}, w: [1]}"},
+		{`{}`, "{}"},
+	}
+
+	for _, tt := range tests {
+		// Iteration order of Go maps varies from run to run, so repeat to catch any dependence on it.
+		for i := 0; i < 20; i++ {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Fatalf("wrong order for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+			}
+		}
 	}
 }
 
@@ -442,6 +990,14 @@ func TestHashIndexExpressions(t *testing.T) {
 		{`{5: 5}[5]`, float32(5)},
 		{`{true: 5}[true]`, float32(5)},
 		{`{false: 5}[false]`, float32(5)},
+		{`{[1, 2]: 5}[[1, 2]]`, float32(5)},
+		{`{[1, 2]: 5}[[2, 1]]`, nil},
+		{`let x = 1; let y = 2; {[x, y]: 5}[[1, 2]]`, float32(5)},
+		{`{[[1], "a"]: 5}[[[1], "a"]]`, float32(5)},
+		{`{{"a": 1, "b": 2}: 5}[{"b": 2, "a": 1}]`, float32(5)},
+		{`{[]: 5}[[]]`, float32(5)},
+		{`{0: 5}[-0]`, float32(5)},
+		{`{[1, 2]: 5, [1, 2]: 6}[[1, 2]]`, float32(6)},
 	}
 
 	for _, tt := range tests {
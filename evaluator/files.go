@@ -0,0 +1,157 @@
+package evaluator
+
+import (
+	"errors"
+	"github.com/butlermatt/monlox/object"
+	"io/fs"
+	"strings"
+)
+
+// fileBuiltins read and write files in the FS of the Caller. Names are slash separated and relative to
+// the root of the FS, as for fs.FS.
+var fileBuiltins = map[string]*object.Builtin{
+	"read_file": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			fsys, name, errObj := fileArgs(c, line, "read_file", 1, args)
+			if errObj != nil {
+				return errObj
+			}
+
+			data, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return newIOError(line, err)
+			}
+
+			return &object.String{Value: string(data)}
+		},
+	},
+	// read_lines returns the lines of a file, without their line endings.
+	"read_lines": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			fsys, name, errObj := fileArgs(c, line, "read_lines", 1, args)
+			if errObj != nil {
+				return errObj
+			}
+
+			data, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return newIOError(line, err)
+			}
+
+			text := strings.TrimSuffix(string(data), "\n")
+			if text == "" {
+				return &object.Array{}
+			}
+
+			lines := strings.Split(text, "\n")
+			for i, l := range lines {
+				lines[i] = strings.TrimSuffix(l, "\r")
+			}
+
+			return stringsToArray(lines)
+		},
+	},
+	"write_file": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			return evalWrite(c, line, "write_file", args, FS.WriteFile)
+		},
+	},
+	"append_file": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			return evalWrite(c, line, "append_file", args, FS.AppendFile)
+		},
+	},
+	// list_dir returns the names of the entries of a directory, sorted.
+	"list_dir": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			fsys, name, errObj := fileArgs(c, line, "list_dir", 1, args)
+			if errObj != nil {
+				return errObj
+			}
+
+			entries, err := fs.ReadDir(fsys, name)
+			if err != nil {
+				return newIOError(line, err)
+			}
+
+			names := make([]string, len(entries))
+			for i, entry := range entries {
+				names[i] = entry.Name()
+			}
+
+			return stringsToArray(names)
+		},
+	},
+	"exists": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			fsys, name, errObj := fileArgs(c, line, "exists", 1, args)
+			if errObj != nil {
+				return errObj
+			}
+
+			_, err := fs.Stat(fsys, name)
+			if errors.Is(err, fs.ErrNotExist) {
+				return False
+			} else if err != nil {
+				return newIOError(line, err)
+			}
+
+			return True
+		},
+	},
+}
+
+// evalWrite writes the string which is the second argument to the builtin name to the file named by the
+// first, using write.
+func evalWrite(c object.Caller, line int, name string, args []object.Object, write func(FS, string, []byte) error) object.Object {
+	fsys, file, errObj := fileArgs(c, line, name, 2, args)
+	if errObj != nil {
+		return errObj
+	}
+
+	data, errObj := stringArg(line, "second argument to `"+name+"`", args[1])
+	if errObj != nil {
+		return errObj
+	}
+
+	if err := write(fsys, file, []byte(data)); err != nil {
+		return newIOError(line, err)
+	}
+
+	return Null
+}
+
+// fileArgs checks the builtin name was given n arguments, the first of which is a file name, returning
+// the FS of the Caller and the file name.
+func fileArgs(c object.Caller, line int, name string, n int, args []object.Object) (FS, string, *object.Error) {
+	if e := expectNArgs(line, n, args); e != nil {
+		return nil, "", e
+	}
+
+	desc := "argument to `" + name + "`"
+	if n > 1 {
+		desc = "first " + desc
+	}
+
+	file, errObj := stringArg(line, desc, args[0])
+	if errObj != nil {
+		return nil, "", errObj
+	}
+
+	fc, ok := c.(FileCaller)
+	if !ok {
+		return nil, "", newUnsupportedError(line, name)
+	}
+
+	fsys := fc.FileSystem()
+	if fsys == nil {
+		return nil, "", newError(line, object.IOError, "file access is not enabled")
+	}
+
+	return fsys, file, nil
+}
+
+// newIOError returns an IOError for err, which caused a file operation to fail.
+func newIOError(line int, err error) *object.Error {
+	return newError(line, object.IOError, "%s", err)
+}
@@ -0,0 +1,80 @@
+package evaluator
+
+import (
+	"github.com/butlermatt/monlox/lexer"
+	"github.com/butlermatt/monlox/memfs"
+	"github.com/butlermatt/monlox/object"
+	"github.com/butlermatt/monlox/parser"
+	"testing"
+	"testing/fstest"
+)
+
+func testEvalFS(input string, fsys FS) object.Object {
+	e := New()
+	e.FS = fsys
+
+	return e.Eval(parser.New(lexer.New(input)).ParseProgram(), object.NewEnvironment())
+}
+
+func newTestFS() *memfs.FS {
+	return &memfs.FS{MapFS: fstest.MapFS{
+		"hello.txt":      {Data: []byte("hello, world")},
+		"lines.txt":      {Data: []byte("one\r\ntwo\n\nfour\n")},
+		"empty.txt":      {Data: []byte{}},
+		"data/b.csv":     {Data: []byte("b")},
+		"data/a.csv":     {Data: []byte("a")},
+		"data/sub/c.csv": {Data: []byte("c")},
+	}}
+}
+
+func TestFileBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`read_file("hello.txt")`, "hello, world"},
+		{`read_file("data/a.csv")`, "a"},
+		{`read_lines("lines.txt")`, "[one, two, , four]"},
+		{`read_lines("hello.txt")`, "[hello, world]"},
+		{`read_lines("empty.txt")`, "[]"},
+		{`list_dir("data")`, "[a.csv, b.csv, sub]"},
+		{`list_dir(".")`, "[data, empty.txt, hello.txt, lines.txt]"},
+		{`exists("hello.txt")`, "true"},
+		{`exists("data")`, "true"},
+		{`exists("missing.txt")`, "false"},
+		{`write_file("out.txt", "report"); read_file("out.txt")`, "report"},
+		{`write_file("hello.txt", "bye"); read_file("hello.txt")`, "bye"},
+		{`write_file("new/dir/out.txt", "x"); list_dir("new")`, "[dir]"},
+		{`write_file("out.txt", "a")`, "null"},
+		{`append_file("log.txt", "a"); append_file("log.txt", "b"); read_file("log.txt")`, "ab"},
+		{`append_file("lines.txt", "five"); read_lines("lines.txt")`, "[one, two, , four, five]"},
+		{`append_file("hello.txt", "!"); read_file("hello.txt")`, "hello, world!"},
+		{`read_file("missing.txt")`, "IOError line 1: open missing.txt: file does not exist"},
+		{`read_file("../secret")`, "IOError line 1: open ../secret: file does not exist"},
+		{`read_file("/etc/passwd")`, "IOError line 1: open /etc/passwd: file does not exist"},
+		{`write_file("../out.txt", "x")`, "IOError line 1: write ../out.txt: invalid argument"},
+		{`append_file("data", "x")`, "IOError line 1: append data: is a directory"},
+		{`try { read_file("missing.txt") } catch (e) { e["kind"] }`, "IOError"},
+		{`read_file(1)`, "TypeError line 1: argument to `read_file` must be STRING, got=NUMBER"},
+		{`write_file(1, "x")`, "TypeError line 1: first argument to `write_file` must be STRING, got=NUMBER"},
+		{`write_file("a.txt", 1)`, "TypeError line 1: second argument to `write_file` must be STRING, got=NUMBER"},
+		{`exists()`, "ArityError line 1: wrong number of arguments. expected=1, got=0"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalFS(tt.input, newTestFS())
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestFileBuiltinsWithoutFS(t *testing.T) {
+	for _, input := range []string{`read_file("a.txt")`, `write_file("a.txt", "x")`, `exists("a.txt")`} {
+		evaluated := testEval(input)
+		expected := "IOError line 1: file access is not enabled"
+		if evaluated.Inspect() != expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", input, expected, evaluated.Inspect())
+		}
+	}
+}
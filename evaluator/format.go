@@ -0,0 +1,252 @@
+package evaluator
+
+import (
+	"fmt"
+	"github.com/butlermatt/monlox/object"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// formatBuiltins build strings from a template, in which each {} placeholder is replaced by an argument.
+//
+// A placeholder names its argument by position, as in {0}, or by a key of a hash given as the first
+// argument, as in {name}. An empty placeholder takes the argument after the one taken by the previous empty
+// placeholder. After a colon, a placeholder may give a spec of the form [[fill]align][0][width][.precision]:
+// align is <, > or ^ for left, right or centered, width is the minimum width in characters, and precision
+// is the number of decimals of a number, or the maximum length of anything else. Numbers are right aligned
+// and other values left aligned unless an align is given. {{ and }} stand for literal braces.
+var formatBuiltins = map[string]*object.Builtin{
+	"format":  formatBuiltin("format"),
+	"sprintf": formatBuiltin("sprintf"),
+	// print writes its formatted arguments without adding a newline.
+	"print": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			oc, ok := c.(OutputCaller)
+			if !ok {
+				return newUnsupportedError(line, "print")
+			}
+
+			str, errObj := formatArgs(line, "print", args)
+			if errObj != nil {
+				return errObj
+			}
+
+			fmt.Fprint(oc.Output(), str)
+
+			return Null
+		},
+	},
+}
+
+// formatBuiltin returns a builtin returning its formatted arguments.
+func formatBuiltin(name string) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			str, errObj := formatArgs(line, name, args)
+			if errObj != nil {
+				return errObj
+			}
+
+			return &object.String{Value: str}
+		},
+	}
+}
+
+// formatArgs returns the template which is the first argument to the builtin name, formatted with the
+// arguments after it.
+func formatArgs(line int, name string, args []object.Object) (string, *object.Error) {
+	if len(args) == 0 {
+		return "", newError(line, object.ArityError, "wrong number of arguments. expected at least 1, got=0")
+	}
+
+	template, errObj := stringArg(line, "first argument to `"+name+"`", args[0])
+	if errObj != nil {
+		return "", errObj
+	}
+
+	return format(line, template, args[1:])
+}
+
+// format returns template with its placeholders replaced by args.
+func format(line int, template string, args []object.Object) (string, *object.Error) {
+	var out strings.Builder
+	next := 0
+
+	for i := 0; i < len(template); i++ {
+		ch := template[i]
+		if ch != '{' && ch != '}' {
+			out.WriteByte(ch)
+			continue
+		}
+
+		if i+1 < len(template) && template[i+1] == ch {
+			out.WriteByte(ch)
+			i++
+			continue
+		}
+
+		if ch == '}' {
+			return "", newError(line, object.ValueError, "unmatched `}` in format string")
+		}
+
+		end := strings.IndexByte(template[i:], '}')
+		if end < 0 {
+			return "", newError(line, object.ValueError, "unmatched `{` in format string")
+		}
+
+		field := template[i+1 : i+end]
+		i += end
+
+		name, spec, _ := strings.Cut(field, ":")
+		var arg object.Object
+		var errObj *object.Error
+		if name == "" {
+			arg, errObj = positionalArg(line, next, args)
+			next++
+		} else if n, err := strconv.Atoi(name); err == nil {
+			arg, errObj = positionalArg(line, n, args)
+		} else {
+			arg, errObj = namedArg(line, name, args)
+		}
+		if errObj != nil {
+			return "", errObj
+		}
+
+		str, errObj := formatValue(line, arg, spec)
+		if errObj != nil {
+			return "", errObj
+		}
+		out.WriteString(str)
+	}
+
+	return out.String(), nil
+}
+
+func positionalArg(line, n int, args []object.Object) (object.Object, *object.Error) {
+	if n < 0 || n >= len(args) {
+		return nil, newError(line, object.IndexError, "format placeholder {%d} out of range with %d arguments", n, len(args))
+	}
+
+	return args[n], nil
+}
+
+func namedArg(line int, name string, args []object.Object) (object.Object, *object.Error) {
+	var hash *object.Hash
+	if len(args) > 0 {
+		hash, _ = args[0].(*object.Hash)
+	}
+	if hash == nil {
+		return nil, newError(line, object.ValueError, "format placeholder {%s} needs a HASH as the first argument", name)
+	}
+
+	value, ok := hash.Get(&object.String{Value: name})
+	if !ok {
+		return nil, newError(line, object.IndexError, "format placeholder {%s} has no value in the hash", name)
+	}
+
+	return value, nil
+}
+
+// formatValue returns the text of arg formatted by spec.
+func formatValue(line int, arg object.Object, spec string) (string, *object.Error) {
+	fill, align, zero, width, precision, ok := parseFormatSpec(spec)
+	if !ok {
+		return "", newError(line, object.ValueError, "invalid format spec %q", spec)
+	}
+	if width > maxStringLength || precision > maxStringLength {
+		return "", newError(line, object.ValueError, "format spec %q is wider than %d characters", spec, maxStringLength)
+	}
+
+	_, isNumber := arg.(*object.Number)
+
+	var text string
+	switch arg := arg.(type) {
+	case *object.Number:
+		if precision >= 0 {
+			text = strconv.FormatFloat(float64(arg.Value), 'f', precision, 32)
+		} else {
+			text = arg.Inspect()
+		}
+	case *object.String:
+		text = arg.Value
+	default:
+		text = arg.Inspect()
+	}
+
+	if !isNumber && precision >= 0 && utf8.RuneCountInString(text) > precision {
+		text = string([]rune(text)[:precision])
+	}
+
+	if zero && align == 0 {
+		if isNumber {
+			// Zeros go between the sign and the digits.
+			sign := ""
+			if strings.HasPrefix(text, "-") {
+				sign, text = "-", text[1:]
+			}
+			return sign + pad(text, width-len(sign), "0"), nil
+		}
+		fill = '0'
+	}
+
+	if align == 0 {
+		align = '<'
+		if isNumber {
+			align = '>'
+		}
+	}
+
+	switch align {
+	case '<':
+		return pad(text, -width, string(fill)), nil
+	case '>':
+		return pad(text, width, string(fill)), nil
+	}
+
+	n := width - utf8.RuneCountInString(text)
+	if n <= 0 {
+		return text, nil
+	}
+	return strings.Repeat(string(fill), n/2) + text + strings.Repeat(string(fill), n-n/2), nil
+}
+
+// parseFormatSpec splits a spec of the form [[fill]align][0][width][.precision]. align is 0 and precision
+// is -1 if they are not given. ok is false if spec is not of that form.
+func parseFormatSpec(spec string) (fill, align rune, zero bool, width, precision int, ok bool) {
+	fill, precision = ' ', -1
+
+	rest := []rune(spec)
+	if len(rest) >= 2 && strings.ContainsRune("<>^", rest[1]) {
+		fill, align, rest = rest[0], rest[1], rest[2:]
+	} else if len(rest) >= 1 && strings.ContainsRune("<>^", rest[0]) {
+		align, rest = rest[0], rest[1:]
+	}
+
+	if len(rest) > 0 && rest[0] == '0' {
+		zero, rest = true, rest[1:]
+	}
+
+	width, rest = leadingNumber(rest)
+
+	if len(rest) > 0 && rest[0] == '.' {
+		if len(rest) == 1 || rest[1] < '0' || rest[1] > '9' {
+			return fill, align, zero, width, precision, false
+		}
+		precision, rest = leadingNumber(rest[1:])
+	}
+
+	return fill, align, zero, width, precision, len(rest) == 0
+}
+
+// leadingNumber returns the number made of the digits at the start of runes, and the runes after it. Numbers
+// above maxStringLength are returned as maxStringLength+1, so that long runs of digits cannot overflow.
+func leadingNumber(runes []rune) (int, []rune) {
+	n := 0
+	for len(runes) > 0 && runes[0] >= '0' && runes[0] <= '9' {
+		n = min(n*10+int(runes[0]-'0'), maxStringLength+1)
+		runes = runes[1:]
+	}
+
+	return n, runes
+}
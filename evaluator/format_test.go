@@ -0,0 +1,85 @@
+package evaluator
+
+import (
+	"bytes"
+	"github.com/butlermatt/monlox/lexer"
+	"github.com/butlermatt/monlox/object"
+	"github.com/butlermatt/monlox/parser"
+	"testing"
+)
+
+func TestFormatBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`format("plain")`, "plain"},
+		{`format("{} + {} = {}", 1, 2, 3)`, "1 + 2 = 3"},
+		{`format("{1} {0} {1}", "a", "b")`, "b a b"},
+		{`format("{name} is {age}", {"name": "Ann", "age": 30})`, "Ann is 30"},
+		{`format("{} and {}", [1, "a"], true)`, "[1, a] and true"},
+		{`format("{{}} {}", 1)`, "{} 1"},
+		{`format("[{:5}]", 42)`, "[   42]"},
+		{`format("[{:5}]", "ab")`, "[ab   ]"},
+		{`format("[{:>5}]", "ab")`, "[   ab]"},
+		{`format("[{:<5}]", 42)`, "[42   ]"},
+		{`format("[{:^6}]", "ab")`, "[  ab  ]"},
+		{`format("[{:^5}]", "ab")`, "[ ab  ]"},
+		{`format("[{:*^7}]", "ab")`, "[**ab***]"},
+		{`format("[{:·>4}]", "é")`, "[···é]"},
+		{`format("{:.2}", pi)`, "3.14"},
+		{`format("{:.0}", 2.5)`, "2"},
+		{`format("[{:8.3}]", -1.5)`, "[  -1.500]"},
+		{`format("{:05}", 42)`, "00042"},
+		{`format("{:06.2}", -1.5)`, "-01.50"},
+		{`format("{:05}", "ab")`, "ab000"},
+		{`format("{:.3}", "abcdef")`, "abc"},
+		{`format("[{:2}]", "abcdef")`, "[abcdef]"},
+		{`format("{name:>6.1}", {"name": 2})`, "   2.0"},
+		{`sprintf("{}-{}", "a", "b")`, "a-b"},
+		{`print("{}", 1)`, "null"},
+		{"format()", "ArityError line 1: wrong number of arguments. expected at least 1, got=0"},
+		{"format(1)", "TypeError line 1: first argument to `format` must be STRING, got=NUMBER"},
+		{"sprintf(1)", "TypeError line 1: first argument to `sprintf` must be STRING, got=NUMBER"},
+		{`format("{} {}", 1)`, "IndexError line 1: format placeholder {1} out of range with 1 arguments"},
+		{`format("{3}", 1)`, "IndexError line 1: format placeholder {3} out of range with 1 arguments"},
+		{`format("{name}", 1)`, "ValueError line 1: format placeholder {name} needs a HASH as the first argument"},
+		{`format("{name}", {})`, "IndexError line 1: format placeholder {name} has no value in the hash"},
+		{`format("{", 1)`, "ValueError line 1: unmatched `{` in format string"},
+		{`format("}", 1)`, "ValueError line 1: unmatched `}` in format string"},
+		{`format("{:x}", 1)`, "ValueError line 1: invalid format spec \"x\""},
+		{`format("{:5.}", 1)`, "ValueError line 1: invalid format spec \"5.\""},
+		{`format("{:20000000}", 1)`, "ValueError line 1: format spec \"20000000\" is wider than 16777216 characters"},
+		{`format("{:.99999999999999999999999}", 1)`, "ValueError line 1: format spec \".99999999999999999999999\" is wider than 16777216 characters"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestPrintOutput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`print("a"); print("b")`, "ab"},
+		{`print("{}: {:.1}", "x", 1)`, "x: 1.0"},
+		{`print("{}!", "hi"); puts(1, 2)`, "hi!1\n2\n"},
+		{`print("a"); print("{}")`, "a"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		e := New()
+		e.Out = &out
+		e.Eval(parser.New(lexer.New(tt.input)).ParseProgram(), object.NewEnvironment())
+
+		if out.String() != tt.expected {
+			t.Errorf("wrong output for %s. expected=%q, got=%q", tt.input, tt.expected, out.String())
+		}
+	}
+}
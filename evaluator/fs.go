@@ -0,0 +1,65 @@
+package evaluator
+
+import (
+	"io/fs"
+	"os"
+)
+
+// FS is a filesystem the file builtins may use. It extends fs.FS, whose rules for valid names it follows,
+// with writing files. A memfs.FS is an FS held in memory, for tests.
+type FS interface {
+	fs.FS
+	// WriteFile writes data to the named file, creating it if needed and replacing what it held.
+	WriteFile(name string, data []byte) error
+	// AppendFile adds data to the end of the named file, creating it if needed.
+	AppendFile(name string, data []byte) error
+}
+
+// DirFS is an FS confined to a directory. Names which would leave the directory, including through
+// symbolic links, are rejected.
+type DirFS struct {
+	fs.FS
+	root *os.Root
+}
+
+// OpenDirFS returns a DirFS confined to the directory dir. It holds the directory open until it is closed.
+func OpenDirFS(dir string) (*DirFS, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DirFS{FS: root.FS(), root: root}, nil
+}
+
+// Close closes the directory. Files may not be read or written once it is closed.
+func (d *DirFS) Close() error {
+	return d.root.Close()
+}
+
+func (d *DirFS) WriteFile(name string, data []byte) error {
+	return d.write("write", name, os.O_TRUNC, data)
+}
+
+func (d *DirFS) AppendFile(name string, data []byte) error {
+	return d.write("append", name, os.O_APPEND, data)
+}
+
+// write writes data to the named file, opened for op with flag as well as for writing and creating it.
+func (d *DirFS) write(op, name string, flag int, data []byte) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	f, err := d.root.OpenFile(name, os.O_WRONLY|os.O_CREATE|flag, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,75 @@
+package evaluator
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirFS(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "in.txt"), []byte("input"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys, err := OpenDirFS(dir)
+	if err != nil {
+		t.Fatalf("OpenDirFS returned an error: %v", err)
+	}
+
+	if data, err := fs.ReadFile(fsys, "in.txt"); err != nil || string(data) != "input" {
+		t.Errorf("wrong contents of in.txt. got=%q, err=%v", data, err)
+	}
+
+	if err := fsys.WriteFile("out.txt", []byte("replaced")); err != nil {
+		t.Fatalf("WriteFile returned an error: %v", err)
+	}
+	if err := fsys.WriteFile("out.txt", []byte("a")); err != nil {
+		t.Fatalf("WriteFile returned an error: %v", err)
+	}
+	if err := fsys.AppendFile("out.txt", []byte("b")); err != nil {
+		t.Fatalf("AppendFile returned an error: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "out.txt")); err != nil || string(data) != "ab" {
+		t.Errorf("wrong contents of out.txt. got=%q, err=%v", data, err)
+	}
+
+	for _, name := range []string{"../secret.txt", "/etc/passwd", "link/secret.txt"} {
+		if _, err := fs.ReadFile(fsys, name); err == nil {
+			t.Errorf("reading %s outside the directory succeeded", name)
+		}
+		if err := fsys.WriteFile(name, []byte("x")); err == nil {
+			t.Errorf("writing %s outside the directory succeeded", name)
+		}
+		if err := fsys.AppendFile(name, []byte("x")); err == nil {
+			t.Errorf("appending to %s outside the directory succeeded", name)
+		}
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(outside, "secret.txt")); string(data) != "secret" {
+		t.Errorf("file outside the directory was changed. got=%q", data)
+	}
+
+	if err := fsys.Close(); err != nil {
+		t.Fatalf("Close returned an error: %v", err)
+	}
+	if _, err := fs.ReadFile(fsys, "in.txt"); err == nil {
+		t.Errorf("reading from a closed DirFS succeeded")
+	}
+	if err := fsys.WriteFile("out.txt", []byte("c")); err == nil {
+		t.Errorf("writing to a closed DirFS succeeded")
+	}
+
+	if _, err := OpenDirFS(filepath.Join(dir, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("wrong error for a missing directory. got=%v", err)
+	}
+}
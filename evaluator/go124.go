@@ -0,0 +1,9 @@
+//go:build !go1.24
+
+package evaluator
+
+// MonLox needs Go 1.24 or later for os.Root, which DirFS is built on. With no go.mod to declare the minimum
+// version in, this file declares it: it is only built by older versions, and refers to a name which is
+// deliberately undefined, so that the build fails with the reason in the error rather than only with errors
+// about undefined APIs.
+var _ = requires_go1_24_or_later
@@ -0,0 +1,165 @@
+package evaluator
+
+import "github.com/butlermatt/monlox/object"
+
+// hashBuiltins work with hashes. Like push, those that change a hash return a changed copy of it, leaving
+// the original as it was. Pairs are listed in the order their keys were added.
+var hashBuiltins = map[string]*object.Builtin{
+	"keys": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			hash, errObj := hashArg(line, "keys", args)
+			if errObj != nil {
+				return errObj
+			}
+
+			var keys []object.Object
+			for _, pair := range hash.Pairs() {
+				keys = append(keys, pair.Key)
+			}
+
+			return &object.Array{Elements: keys}
+		},
+	},
+	"values": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			hash, errObj := hashArg(line, "values", args)
+			if errObj != nil {
+				return errObj
+			}
+
+			var values []object.Object
+			for _, pair := range hash.Pairs() {
+				values = append(values, pair.Value)
+			}
+
+			return &object.Array{Elements: values}
+		},
+	},
+	"entries": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			hash, errObj := hashArg(line, "entries", args)
+			if errObj != nil {
+				return errObj
+			}
+
+			var entries []object.Object
+			for _, pair := range hash.Pairs() {
+				entries = append(entries, &object.Array{Elements: []object.Object{pair.Key, pair.Value}})
+			}
+
+			return &object.Array{Elements: entries}
+		},
+	},
+	"has": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 2, args); e != nil {
+				return e
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newArgumentError(line, "first argument to `has` must be HASH, got=%s", args[0])
+			}
+			if _, ok := args[1].(object.Hashable); !ok {
+				return newHashKeyError(line, args[1])
+			}
+
+			_, ok = hash.Get(args[1])
+			return nativeBooltoObject(ok)
+		},
+	},
+	"delete": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 2, args); e != nil {
+				return e
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newArgumentError(line, "first argument to `delete` must be HASH, got=%s", args[0])
+			}
+			if _, ok := args[1].(object.Hashable); !ok {
+				return newHashKeyError(line, args[1])
+			}
+
+			result := copyHash(hash)
+			result.Delete(args[1])
+
+			return result
+		},
+	},
+	"merge": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			return evalMerge(line, "merge", false, args)
+		},
+	},
+	"deep_merge": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			return evalMerge(line, "deep_merge", true, args)
+		},
+	},
+}
+
+// evalMerge merges the hashes in args into a new hash, with the values of later hashes replacing those of
+// earlier ones. If deep is set, two hashes stored under the same key are merged rather than replaced.
+func evalMerge(line int, name string, deep bool, args []object.Object) object.Object {
+	if len(args) == 0 {
+		return newError(line, object.ArityError, "wrong number of arguments. expected at least 1, got=0")
+	}
+
+	result := object.NewHash()
+	for _, arg := range args {
+		hash, ok := arg.(*object.Hash)
+		if !ok {
+			return newArgumentError(line, "arguments to `"+name+"` must be HASH, got=%s", arg)
+		}
+
+		mergeInto(result, hash, deep)
+	}
+
+	return result
+}
+
+// mergeInto sets the pairs of src in dst. If deep is set, a hash in src stored under the same key as a hash
+// in dst is merged into a copy of it instead.
+func mergeInto(dst, src *object.Hash, deep bool) {
+	for _, pair := range src.Pairs() {
+		value := pair.Value
+
+		if deep {
+			existing, _ := dst.Get(pair.Key)
+			current, ok := existing.(*object.Hash)
+			incoming, incomingOk := value.(*object.Hash)
+			if ok && incomingOk {
+				merged := copyHash(current)
+				mergeInto(merged, incoming, true)
+				value = merged
+			}
+		}
+
+		dst.Set(pair.Key, value)
+	}
+}
+
+func copyHash(hash *object.Hash) *object.Hash {
+	result := object.NewHash()
+	for _, pair := range hash.Pairs() {
+		result.Set(pair.Key, pair.Value)
+	}
+
+	return result
+}
+
+// hashArg returns the hash which is the only argument to the builtin name.
+func hashArg(line int, name string, args []object.Object) (*object.Hash, *object.Error) {
+	if e := expectNArgs(line, 1, args); e != nil {
+		return nil, e
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return nil, newArgumentError(line, "argument to `"+name+"` must be HASH, got=%s", args[0])
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,44 @@
+package evaluator
+
+import "testing"
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`keys({"b": 1, "a": 2})`, "[b, a]"},
+		{`keys({})`, "[]"},
+		{`len({"a": 1, "b": 2})`, "2"},
+		{`values({"b": 1, "a": 2})`, "[1, 2]"},
+		{`entries({"b": 1, "a": 2})`, "[[b, 1], [a, 2]]"},
+		{`has({"a": 1}, "a")`, "true"},
+		{`has({"a": 1}, "b")`, "false"},
+		{`has({[1, 2]: 1}, [1, 2])`, "true"},
+		{`delete({"a": 1, "b": 2, "c": 3}, "b")`, "{a: 1, c: 3}"},
+		{`delete({"a": 1}, "z")`, "{a: 1}"},
+		{`let h = {"a": 1}; delete(h, "a"); h`, "{a: 1}"},
+		{`merge({"a": 1, "b": 2}, {"b": 3, "c": 4})`, "{a: 1, b: 3, c: 4}"},
+		{`merge({"a": 1}, {"b": 2}, {"a": 3})`, "{a: 3, b: 2}"},
+		{`merge({"a": 1})`, "{a: 1}"},
+		{`merge({"x": {"a": 1}}, {"x": {"b": 2}})`, "{x: {b: 2}}"},
+		{`deep_merge({"x": {"a": 1, "b": 1}, "y": 1}, {"x": {"b": 2}})`, "{x: {a: 1, b: 2}, y: 1}"},
+		{`deep_merge({"x": {"y": {"a": 1}}}, {"x": {"y": {"b": 2}}})`, "{x: {y: {a: 1, b: 2}}}"},
+		{`deep_merge({"x": {"a": 1}}, {"x": 5})`, "{x: 5}"},
+		{`let base = {"x": {"a": 1}}; deep_merge(base, {"x": {"b": 2}}); base`, "{x: {a: 1}}"},
+		{`keys([1])`, "TypeError line 1: argument to `keys` must be HASH, got=ARRAY"},
+		{`values({}, {})`, "ArityError line 1: wrong number of arguments. expected=1, got=2"},
+		{`has([], 1)`, "TypeError line 1: first argument to `has` must be HASH, got=ARRAY"},
+		{`has({}, fn() {})`, "TypeError line 1: unusable as hash key: FUNCTION"},
+		{`delete({}, fn() {})`, "TypeError line 1: unusable as hash key: FUNCTION"},
+		{`merge({}, 1)`, "TypeError line 1: arguments to `merge` must be HASH, got=NUMBER"},
+		{`deep_merge()`, "ArityError line 1: wrong number of arguments. expected at least 1, got=0"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/butlermatt/monlox/object"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsonBuiltins convert between values and JSON text.
+var jsonBuiltins = map[string]*object.Builtin{
+	// json_parse returns the value described by a JSON document. Objects become hashes, keeping the order
+	// of their keys.
+	"json_parse": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			text, errObj := stringArg(line, "argument to `json_parse`", args[0])
+			if errObj != nil {
+				return errObj
+			}
+
+			dec := json.NewDecoder(strings.NewReader(text))
+			dec.UseNumber()
+
+			value, err := decodeJSON(dec)
+			if err == nil {
+				if _, err = dec.Token(); err == io.EOF {
+					return value
+				} else if err == nil {
+					err = errors.New("unexpected data after the end of the value")
+				}
+			}
+			if err == io.EOF {
+				err = errors.New("unexpected end of input")
+			}
+
+			return newError(line, object.ValueError, "invalid JSON: %s", err)
+		},
+	},
+	// json_stringify returns the JSON text of a value, writing sets as arrays. An optional indent, either a
+	// number of spaces or a string, spreads it over several lines, and an optional true sorts the keys of
+	// hashes.
+	"json_stringify": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectArgsBetween(line, 1, 3, args); e != nil {
+				return e
+			}
+
+			enc := &jsonEncoder{line: line, seen: make(map[object.Object]bool)}
+
+			if len(args) > 1 {
+				switch indent := args[1].(type) {
+				case *object.String:
+					if n := utf8.RuneCountInString(indent.Value); n > maxStringLength {
+						return newError(line, object.ValueError, "`json_stringify` indent must be at most %d characters, got=%d", maxStringLength, n)
+					}
+					enc.indent = indent.Value
+				case *object.Number:
+					n, errObj := intArg(line, "second argument to `json_stringify`", indent)
+					if errObj != nil {
+						return errObj
+					}
+					if n > maxStringLength {
+						return newError(line, object.ValueError, "`json_stringify` indent must be at most %d characters, got=%d", maxStringLength, n)
+					}
+					enc.indent = strings.Repeat(" ", max(n, 0))
+				default:
+					return newArgumentError(line, "second argument to `json_stringify` must be NUMBER or STRING, got=%s", indent)
+				}
+			}
+
+			if len(args) > 2 {
+				sortKeys, ok := args[2].(*object.Boolean)
+				if !ok {
+					return newArgumentError(line, "third argument to `json_stringify` must be BOOLEAN, got=%s", args[2])
+				}
+				enc.sortKeys = sortKeys.Value
+			}
+
+			if errObj := enc.encode(args[0], 0); errObj != nil {
+				return errObj
+			}
+
+			return &object.String{Value: enc.out.String()}
+		},
+	},
+}
+
+// decodeJSON decodes the next JSON value from dec.
+func decodeJSON(dec *json.Decoder) (object.Object, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			arr := &object.Array{}
+			for dec.More() {
+				el, err := decodeJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				arr.Elements = append(arr.Elements, el)
+			}
+
+			_, err := dec.Token()
+			return arr, err
+		}
+
+		hash := object.NewHash()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(&object.String{Value: key.(string)}, value)
+		}
+
+		_, err := dec.Token()
+		return hash, err
+	case json.Number:
+		f, err := tok.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &object.Number{Value: float32(f)}, nil
+	case string:
+		return &object.String{Value: tok}, nil
+	case bool:
+		return nativeBooltoObject(tok), nil
+	}
+
+	return Null, nil
+}
+
+// jsonEncoder writes values as JSON text.
+type jsonEncoder struct {
+	line     int
+	indent   string // Indent for each level of nesting. Empty for compact output.
+	sortKeys bool
+
+	out  bytes.Buffer
+	seen map[object.Object]bool // Arrays and hashes being encoded, to detect cycles.
+}
+
+func (enc *jsonEncoder) encode(obj object.Object, depth int) *object.Error {
+	switch obj := obj.(type) {
+	case *object.Null:
+		enc.out.WriteString("null")
+	case *object.Boolean:
+		enc.out.WriteString(strconv.FormatBool(obj.Value))
+	case *object.Number:
+		if math.IsNaN(float64(obj.Value)) || math.IsInf(float64(obj.Value), 0) {
+			return newError(enc.line, object.ValueError, "cannot convert %s to JSON", obj.Inspect())
+		}
+		enc.out.WriteString(obj.Inspect())
+	case *object.String:
+		enc.writeString(obj.Value)
+	case *object.Array:
+		return enc.encodeList(obj, obj.Elements, depth)
+	case *object.Set:
+		return enc.encodeList(obj, obj.Elements(), depth)
+	case *object.Hash:
+		return enc.encodeHash(obj, depth)
+	default:
+		errObj := newError(enc.line, object.TypeError, "cannot convert %s to JSON", obj.Type())
+		errObj.Operands = []object.Object{obj}
+		return errObj
+	}
+
+	return nil
+}
+
+func (enc *jsonEncoder) encodeList(list object.Object, elements []object.Object, depth int) *object.Error {
+	if errObj := enc.enter(list); errObj != nil {
+		return errObj
+	}
+	defer delete(enc.seen, list)
+
+	enc.out.WriteByte('[')
+	for i, el := range elements {
+		if i > 0 {
+			enc.out.WriteByte(',')
+		}
+		enc.newLine(depth + 1)
+
+		if errObj := enc.encode(el, depth+1); errObj != nil {
+			return errObj
+		}
+	}
+	if len(elements) > 0 {
+		enc.newLine(depth)
+	}
+	enc.out.WriteByte(']')
+
+	return nil
+}
+
+func (enc *jsonEncoder) encodeHash(hash *object.Hash, depth int) *object.Error {
+	if errObj := enc.enter(hash); errObj != nil {
+		return errObj
+	}
+	defer delete(enc.seen, hash)
+
+	pairs := hash.Pairs()
+	for _, pair := range pairs {
+		if _, ok := pair.Key.(*object.String); !ok {
+			errObj := newError(enc.line, object.TypeError, "JSON object keys must be STRING, got=%s", pair.Key.Type())
+			errObj.Operands = []object.Object{pair.Key}
+			return errObj
+		}
+	}
+
+	if enc.sortKeys {
+		sort.SliceStable(pairs, func(i, j int) bool {
+			return pairs[i].Key.(*object.String).Value < pairs[j].Key.(*object.String).Value
+		})
+	}
+
+	enc.out.WriteByte('{')
+	for i, pair := range pairs {
+		if i > 0 {
+			enc.out.WriteByte(',')
+		}
+		enc.newLine(depth + 1)
+
+		enc.writeString(pair.Key.(*object.String).Value)
+		enc.out.WriteByte(':')
+		if enc.indent != "" {
+			enc.out.WriteByte(' ')
+		}
+
+		if errObj := enc.encode(pair.Value, depth+1); errObj != nil {
+			return errObj
+		}
+	}
+	if len(pairs) > 0 {
+		enc.newLine(depth)
+	}
+	enc.out.WriteByte('}')
+
+	return nil
+}
+
+// enter records that the container obj is being encoded, returning an error if it already is, as it
+// must then contain itself.
+func (enc *jsonEncoder) enter(obj object.Object) *object.Error {
+	if enc.seen[obj] {
+		return newError(enc.line, object.ValueError, "cannot convert %s to JSON: it contains itself", obj.Type())
+	}
+	enc.seen[obj] = true
+
+	return nil
+}
+
+// newLine starts a new line indented to depth, if the output is indented.
+func (enc *jsonEncoder) newLine(depth int) {
+	if enc.indent == "" {
+		return
+	}
+
+	enc.out.WriteByte('\n')
+	enc.out.WriteString(strings.Repeat(enc.indent, depth))
+}
+
+func (enc *jsonEncoder) writeString(s string) {
+	var buf bytes.Buffer
+
+	// Strings are written as they are, without the escaping of HTML characters json.Marshal would add.
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	e.Encode(s)
+
+	enc.out.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
@@ -0,0 +1,126 @@
+package evaluator
+
+import (
+	"github.com/butlermatt/monlox/lexer"
+	"github.com/butlermatt/monlox/object"
+	"github.com/butlermatt/monlox/parser"
+	"strings"
+	"testing"
+)
+
+// testEvalJSON evaluates input with the variable text bound to the JSON text given, as string literals
+// cannot hold the double quotes JSON is full of.
+func testEvalJSON(input, text string) object.Object {
+	env := object.NewEnvironment()
+	env.Set("text", &object.String{Value: text})
+
+	return Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+}
+
+func TestJSONParse(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{`1.5`, "1.5"},
+		{`-2e3`, "-2000"},
+		{`true`, "true"},
+		{`null`, "null"},
+		{` [1, "a", [], {}] `, "[1, a, [], {}]"},
+		{`{"b": 1, "a": {"c": [true, null]}}`, "{b: 1, a: {c: [true, null]}}"},
+		{`{"a": 1, "a": 2}`, "{a: 2}"},
+		{`"café \"x\""`, `café "x"`},
+		{``, "ValueError line 1: invalid JSON: unexpected end of input"},
+		{`[1] 2`, "ValueError line 1: invalid JSON: unexpected data after the end of the value"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalJSON("json_parse(text)", tt.text)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.text, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	// Other syntax errors are described by encoding/json, whose wording is not relied on.
+	for _, text := range []string{`[1,`, `{1: 2}`, `[1 2]`, `tru`, `{"a" 1}`} {
+		errObj, ok := testEvalJSON("json_parse(text)", text).(*object.Error)
+		if !ok || errObj.Kind != object.ValueError || !strings.HasPrefix(errObj.Message, "invalid JSON: ") {
+			t.Errorf("wrong error for %s. got=%v", text, errObj)
+		}
+	}
+
+	evaluated := testEval("json_parse(1)")
+	if evaluated.Inspect() != "TypeError line 1: argument to `json_parse` must be STRING, got=NUMBER" {
+		t.Errorf("wrong error for non-string argument. got=%q", evaluated.Inspect())
+	}
+}
+
+func TestJSONStringify(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`json_stringify(1.5)`, "1.5"},
+		{`json_stringify(true)`, "true"},
+		{`json_stringify(if (false) { 1 })`, "null"},
+		{`json_stringify("a <b> & é")`, `"a <b> & é"`},
+		{`json_stringify([1, "a", [], {}])`, `[1,"a",[],{}]`},
+		{`json_stringify({"b": 1, "a": [true]})`, `{"b":1,"a":[true]}`},
+		{`json_stringify({"b": 1, "a": 2}, 0, true)`, `{"a":2,"b":1}`},
+		{`json_stringify(#{1, 2})`, `[1,2]`},
+		{`json_stringify({"b": [1, 2], "a": {}}, 2)`, "{\n  \"b\": [\n    1,\n    2\n  ],\n  \"a\": {}\n}"},
+		{"json_stringify({\"b\": 1, \"a\": {\"d\": 1, \"c\": 2}}, \"\t\", true)", "{\n\t\"a\": {\n\t\t\"c\": 2,\n\t\t\"d\": 1\n\t},\n\t\"b\": 1\n}"},
+		{`json_stringify([fn() {}])`, "TypeError line 1: cannot convert FUNCTION to JSON"},
+		{`json_stringify(len)`, "TypeError line 1: cannot convert BUILTIN to JSON"},
+		{`json_stringify({1: 2})`, "TypeError line 1: JSON object keys must be STRING, got=NUMBER"},
+		{`json_stringify(1 / 0)`, "ValueError line 1: cannot convert +Inf to JSON"},
+		{`json_stringify([1], 1000000000)`, "ValueError line 1: `json_stringify` indent must be at most 16777216 characters, got=1000000000"},
+		{`json_stringify([1], repeat(" ", 10000000) + repeat(" ", 10000000))`, "ValueError line 1: `json_stringify` indent must be at most 16777216 characters, got=20000000"},
+		{`json_stringify(1, true)`, "TypeError line 1: second argument to `json_stringify` must be NUMBER or STRING, got=BOOLEAN"},
+		{`json_stringify(1, 2, "yes")`, "TypeError line 1: third argument to `json_stringify` must be BOOLEAN, got=STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []string{
+		`{"name":"monlox","version":1.5,"tags":["a","b"],"nested":{"ok":true,"none":null},"empty":[]}`,
+		`[1,-2.25,"é \"quoted\" \\ slash\n",{"z":1,"a":2}]`,
+		`"just a string"`,
+	}
+
+	for _, text := range tests {
+		evaluated := testEvalJSON("json_stringify(json_parse(text))", text)
+		if evaluated.Inspect() != text {
+			t.Errorf("round trip changed JSON. expected=%q, got=%q", text, evaluated.Inspect())
+		}
+	}
+}
+
+func TestJSONCycles(t *testing.T) {
+	arr := &object.Array{}
+	arr.Elements = []object.Object{arr}
+
+	result := jsonBuiltins["json_stringify"].Fn(New(), 1, arr)
+	errObj, ok := result.(*object.Error)
+	if !ok {
+		t.Fatalf("object is unexpected type. expected=*object.Error, got=%T (%+[1]v)", result)
+	}
+
+	if errObj.Message != "cannot convert ARRAY to JSON: it contains itself" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+
+	// The same value appearing twice without containing itself is not a cycle.
+	inner := &object.Array{Elements: []object.Object{&object.Number{Value: 1}}}
+	result = jsonBuiltins["json_stringify"].Fn(New(), 1, &object.Array{Elements: []object.Object{inner, inner}})
+	if result.Inspect() != "[[1],[1]]" {
+		t.Errorf("wrong JSON for shared value. got=%q", result.Inspect())
+	}
+}
@@ -0,0 +1,197 @@
+package evaluator
+
+import (
+	"github.com/butlermatt/monlox/object"
+	"math"
+)
+
+// constants are predefined values which, like builtins, may be shadowed by variables of the same name.
+var constants = map[string]object.Object{
+	"pi": &object.Number{Value: math.Pi},
+	"e":  &object.Number{Value: math.E},
+}
+
+// mathBuiltins work with numbers. They are calculated at float64 precision before being rounded back to a
+// Number.
+var mathBuiltins = map[string]*object.Builtin{
+	"sqrt":  unaryMath("sqrt", math.Sqrt),
+	"abs":   unaryMath("abs", math.Abs),
+	"floor": unaryMath("floor", math.Floor),
+	"ceil":  unaryMath("ceil", math.Ceil),
+	"round": unaryMath("round", math.Round),
+	"exp":   unaryMath("exp", math.Exp),
+	"sin":   unaryMath("sin", math.Sin),
+	"cos":   unaryMath("cos", math.Cos),
+	"tan":   unaryMath("tan", math.Tan),
+	"asin":  unaryMath("asin", math.Asin),
+	"acos":  unaryMath("acos", math.Acos),
+	"atan":  unaryMath("atan", math.Atan),
+	"pow":   binaryMath("pow", math.Pow),
+	"atan2": binaryMath("atan2", math.Atan2),
+	"log": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectArgsBetween(line, 1, 2, args); e != nil {
+				return e
+			}
+
+			x, errObj := numberArg(line, "first argument to `log`", args[0])
+			if errObj != nil {
+				return errObj
+			}
+
+			if len(args) == 1 {
+				return mathResult(line, "log", math.Log(x), x)
+			}
+
+			base, errObj := numberArg(line, "second argument to `log`", args[1])
+			if errObj != nil {
+				return errObj
+			}
+
+			return mathResult(line, "log", math.Log(x)/math.Log(base), x, base)
+		},
+	},
+	"min": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			return evalExtreme(line, "min", -1, args)
+		},
+	},
+	"max": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			return evalExtreme(line, "max", 1, args)
+		},
+	},
+	// random returns a number in [0, 1), or with arguments a whole number in [0, max) or [min, max).
+	"random": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectArgsBetween(line, 0, 2, args); e != nil {
+				return e
+			}
+
+			rc, ok := c.(RandomCaller)
+			if !ok {
+				return newUnsupportedError(line, "random")
+			}
+
+			rng := rc.Random()
+			if len(args) == 0 {
+				return &object.Number{Value: float32(rng.Float64())}
+			}
+
+			var bounds []int
+			for i, desc := range []string{"first", "second"}[:len(args)] {
+				n, errObj := intArg(line, desc+" argument to `random`", args[i])
+				if errObj != nil {
+					return errObj
+				}
+				bounds = append(bounds, n)
+			}
+
+			lo, hi := 0, bounds[0]
+			if len(bounds) == 2 {
+				lo, hi = bounds[0], bounds[1]
+			}
+
+			if hi <= lo {
+				return newError(line, object.ValueError, "`random` range is empty: %d to %d", lo, hi)
+			}
+
+			return &object.Number{Value: float32(lo + rng.Intn(hi-lo))}
+		},
+	},
+}
+
+// unaryMath returns a builtin applying fn to its one argument.
+func unaryMath(name string, fn func(float64) float64) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			x, errObj := numberArg(line, "argument to `"+name+"`", args[0])
+			if errObj != nil {
+				return errObj
+			}
+
+			return mathResult(line, name, fn(x), x)
+		},
+	}
+}
+
+// binaryMath returns a builtin applying fn to its two arguments.
+func binaryMath(name string, fn func(float64, float64) float64) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 2, args); e != nil {
+				return e
+			}
+
+			x, errObj := numberArg(line, "first argument to `"+name+"`", args[0])
+			if errObj != nil {
+				return errObj
+			}
+			y, errObj := numberArg(line, "second argument to `"+name+"`", args[1])
+			if errObj != nil {
+				return errObj
+			}
+
+			return mathResult(line, name, fn(x, y), x, y)
+		},
+	}
+}
+
+// mathResult returns result as a Number, or a ValueError if name is not defined for inputs, which Go
+// reports by returning NaN for inputs that are not NaN themselves.
+func mathResult(line int, name string, result float64, inputs ...float64) object.Object {
+	if math.IsNaN(result) {
+		for _, x := range inputs {
+			if math.IsNaN(x) {
+				return &object.Number{Value: float32(result)}
+			}
+		}
+
+		return newError(line, object.ValueError, "math domain error: `%s` is not defined for %v", name, inputs)
+	}
+
+	return &object.Number{Value: float32(result)}
+}
+
+// evalExtreme returns the smallest (sign -1) or largest (sign 1) of args, which are either numbers, or a
+// single array of numbers.
+func evalExtreme(line int, name string, sign float32, args []object.Object) object.Object {
+	if len(args) == 1 {
+		if arr, ok := args[0].(*object.Array); ok {
+			args = arr.Elements
+		}
+	}
+
+	if len(args) == 0 {
+		return newError(line, object.ValueError, "`%s` of no numbers", name)
+	}
+
+	var best *object.Number
+	for _, arg := range args {
+		num, ok := arg.(*object.Number)
+		if !ok {
+			return newArgumentError(line, "arguments to `"+name+"` must be NUMBER, got=%s", arg)
+		}
+
+		if best == nil || num.Value*sign > best.Value*sign {
+			best = num
+		}
+	}
+
+	return best
+}
+
+// numberArg returns the value of arg, or a TypeError if it is not a number. desc describes the argument in
+// the error.
+func numberArg(line int, desc string, arg object.Object) (float64, *object.Error) {
+	num, ok := arg.(*object.Number)
+	if !ok {
+		return 0, newArgumentError(line, desc+" must be NUMBER, got=%s", arg)
+	}
+
+	return float64(num.Value), nil
+}
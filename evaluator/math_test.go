@@ -0,0 +1,95 @@
+package evaluator
+
+import (
+	"github.com/butlermatt/monlox/lexer"
+	"github.com/butlermatt/monlox/object"
+	"github.com/butlermatt/monlox/parser"
+	"math/rand"
+	"testing"
+)
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"sqrt(16)", "4"},
+		{"abs(-2.5)", "2.5"},
+		{"floor(2.7)", "2"},
+		{"floor(-2.2)", "-3"},
+		{"ceil(2.2)", "3"},
+		{"round(2.5)", "3"},
+		{"round(-2.5)", "-3"},
+		{"round(2.4)", "2"},
+		{"pow(2, 10)", "1024"},
+		{"pow(9, 0.5)", "3"},
+		{"exp(0)", "1"},
+		{"sin(0)", "0"},
+		{"cos(0)", "1"},
+		{"round(tan(pi / 4))", "1"},
+		{"round(asin(1) * 2 / pi)", "1"},
+		{"acos(1)", "0"},
+		{"atan(0)", "0"},
+		{"atan2(0, 1)", "0"},
+		{"log(1)", "0"},
+		{"round(log(e) * 1000)", "1000"},
+		{"log(8, 2)", "3"},
+		{"min(3, 1, 2)", "1"},
+		{"max(3, 1, 2)", "3"},
+		{"min([4, -1, 7])", "-1"},
+		{"max(5)", "5"},
+		{"pi > 3.14 and pi < 3.15", "true"},
+		{"let pi = 3; pi", "3"},
+		{`try { throw "x" } catch (e) { e["message"] }`, "x"},
+		{"sqrt(-1)", "ValueError line 1: math domain error: `sqrt` is not defined for [-1]"},
+		{"log(-1, 2)", "ValueError line 1: math domain error: `log` is not defined for [-1 2]"},
+		{`sqrt("4")`, "TypeError line 1: argument to `sqrt` must be NUMBER, got=STRING"},
+		{"pow(2)", "ArityError line 1: wrong number of arguments. expected=2, got=1"},
+		{`max(1, "2")`, "TypeError line 1: arguments to `max` must be NUMBER, got=STRING"},
+		{"min([])", "ValueError line 1: `min` of no numbers"},
+		{"random(0)", "ValueError line 1: `random` range is empty: 0 to 0"},
+		{"random(5, 2)", "ValueError line 1: `random` range is empty: 5 to 2"},
+		{"random(1.5)", "ValueError line 1: first argument to `random` must be a whole number, got=1.5"},
+		{"random(1)", "0"},
+		{"random(3, 4)", "3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestRandomRange(t *testing.T) {
+	input := `let check = fn(n) {
+  if (n == 0) { return true }
+  let f = random();
+  let i = random(10);
+  let j = random(-5, 5);
+  if (f < 0 or f >= 1 or i < 0 or i >= 10 or j < -5 or j >= 5 or floor(i) != i) { return false }
+  check(n - 1)
+};
+check(500)`
+
+	testBooleanObject(t, testEval(input), true)
+}
+
+func TestRandomSeed(t *testing.T) {
+	program := parser.New(lexer.New("[random(), random(1000), random(-50, 50)]")).ParseProgram()
+
+	run := func(seed int64) string {
+		e := New()
+		e.Rand = rand.New(rand.NewSource(seed))
+		return e.Eval(program, object.NewEnvironment()).Inspect()
+	}
+
+	if first, second := run(42), run(42); first != second {
+		t.Errorf("runs with the same seed differ. first=%s, second=%s", first, second)
+	}
+
+	if run(42) == run(43) {
+		t.Errorf("runs with different seeds are the same. got=%s", run(42))
+	}
+}
@@ -0,0 +1,153 @@
+package evaluator
+
+import (
+	"github.com/butlermatt/monlox/object"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// regexCacheSize is the number of compiled patterns compileRegex keeps for reuse.
+const regexCacheSize = 256
+
+// regexCache holds the patterns compiled by compileRegex. It is shared by all Evaluators, which may run
+// concurrently.
+var regexCache = struct {
+	sync.Mutex
+	patterns map[string]*regexp.Regexp
+}{patterns: make(map[string]*regexp.Regexp)}
+
+// regexBuiltins match strings against regular expressions, in the syntax of Go's regexp package, made by the
+// regex builtin. Recently compiled patterns are cached, so making the same regex again is cheap. As for split
+// and replace, which also take a regex, a string given in place of a regex matches itself literally.
+var regexBuiltins = map[string]*object.Builtin{
+	"regex": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Regex:
+				return arg
+			case *object.String:
+				re, errObj := compileRegex(line, arg.Value)
+				if errObj != nil {
+					return errObj
+				}
+				return &object.Regex{Regexp: re}
+			}
+
+			return newArgumentError(line, "argument to `regex` must be STRING or REGEX, got=%s", args[0])
+		},
+	},
+	// match returns the first match of a pattern in a string as an array of the matched text followed by
+	// the text of each capture group, or null if there is no match. Groups which did not take part in the
+	// match are null.
+	"match": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			str, re, errObj := regexArgs(line, "match", args)
+			if errObj != nil {
+				return errObj
+			}
+
+			indexes := re.FindStringSubmatchIndex(str)
+			if indexes == nil {
+				return Null
+			}
+
+			return submatchArray(str, indexes)
+		},
+	},
+	// find_all returns every match of a pattern in a string. Matches are strings if the pattern has no
+	// capture groups, and otherwise arrays as returned by match.
+	"find_all": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			str, re, errObj := regexArgs(line, "find_all", args)
+			if errObj != nil {
+				return errObj
+			}
+
+			matches := []object.Object{}
+			for _, indexes := range re.FindAllStringSubmatchIndex(str, -1) {
+				if re.NumSubexp() == 0 {
+					matches = append(matches, &object.String{Value: str[indexes[0]:indexes[1]]})
+				} else {
+					matches = append(matches, submatchArray(str, indexes))
+				}
+			}
+
+			return &object.Array{Elements: matches}
+		},
+	},
+}
+
+// submatchArray returns the text of a match and its capture groups in str, as located by indexes.
+func submatchArray(str string, indexes []int) *object.Array {
+	elements := make([]object.Object, len(indexes)/2)
+	for i := range elements {
+		start, end := indexes[2*i], indexes[2*i+1]
+		if start < 0 {
+			elements[i] = Null
+		} else {
+			elements[i] = &object.String{Value: str[start:end]}
+		}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// regexArgs returns the string and pattern which are the two arguments to the builtin name.
+func regexArgs(line int, name string, args []object.Object) (string, *regexp.Regexp, *object.Error) {
+	if e := expectNArgs(line, 2, args); e != nil {
+		return "", nil, e
+	}
+
+	str, errObj := stringArg(line, "first argument to `"+name+"`", args[0])
+	if errObj != nil {
+		return "", nil, errObj
+	}
+
+	re, errObj := regexArg(line, "second argument to `"+name+"`", args[1])
+	if errObj != nil {
+		return "", nil, errObj
+	}
+
+	return str, re, nil
+}
+
+// regexArg returns the pattern arg, which is either a Regex or a String matching itself literally, or an
+// error if it is neither. desc describes the argument in the error.
+func regexArg(line int, desc string, arg object.Object) (*regexp.Regexp, *object.Error) {
+	switch arg := arg.(type) {
+	case *object.Regex:
+		return arg.Regexp, nil
+	case *object.String:
+		return compileRegex(line, regexp.QuoteMeta(arg.Value))
+	}
+
+	return nil, newArgumentError(line, desc+" must be STRING or REGEX, got=%s", arg)
+}
+
+// compileRegex compiles pattern, reusing the result of an earlier compile of the same pattern if it is
+// still cached. The cache is emptied once it is full.
+func compileRegex(line int, pattern string) (*regexp.Regexp, *object.Error) {
+	regexCache.Lock()
+	defer regexCache.Unlock()
+
+	if re, ok := regexCache.patterns[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, newError(line, object.ValueError, "invalid regex: %s", strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+	}
+
+	if len(regexCache.patterns) >= regexCacheSize {
+		clear(regexCache.patterns)
+	}
+	regexCache.patterns[pattern] = re
+
+	return re, nil
+}
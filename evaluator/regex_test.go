@@ -0,0 +1,80 @@
+package evaluator
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRegexBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`regex("a+b")`, "/a+b/"},
+		{`type(regex("a"))`, "REGEX"},
+		{`is_regex(regex("a"))`, "true"},
+		{`is_regex("a")`, "false"},
+		{`regex("a") == regex("a")`, "true"},
+		{`regex("a") == regex("b")`, "false"},
+		{`let r = regex("x"); regex(r) == r`, "true"},
+		{`match("order 66 and 7", regex("\d+"))`, "[66]"},
+		{`match("2024-05-17", regex("(\d+)-(\d+)-(\d+)"))`, "[2024-05-17, 2024, 05, 17]"},
+		{`match("ab", regex("a(x)?(b)"))`, "[ab, null, b]"},
+		{`match("abc", regex("\d"))`, "null"},
+		{`if (match("abc", "b")) { "yes" } else { "no" }`, "yes"},
+		{`match("a.c", ".")`, "[.]"},
+		{`match("abc", ".")`, "null"},
+		{`match("1+1", "\d+")`, "null"},
+		{`match("key=value", regex("(?P<k>\w+)=(?P<v>\w+)"))`, "[key=value, key, value]"},
+		{`find_all("a1 b22 c333", regex("\d+"))`, "[1, 22, 333]"},
+		{`find_all("a=1, b=2", regex("(\w)=(\d)"))`, "[[a=1, a, 1], [b=2, b, 2]]"},
+		{`find_all("abc", regex("\d"))`, "[]"},
+		{`find_all("a+b+c", "+")`, "[+, +]"},
+		{`replace("2024-05-17", regex("(\d+)-(\d+)-(\d+)"), "$3/$2/$1")`, "17/05/2024"},
+		{`replace("key=value", regex("(?P<k>\w+)=(?P<v>\w+)"), "${v}=${k}")`, "value=key"},
+		{`replace("a.b.c", ".", "-")`, "a-b-c"},
+		{`replace("a.b.c", regex("."), "-")`, "-----"},
+		{`replace("cost: 5", regex("\d"), "$$")`, "cost: $"},
+		{`replace("a1b2", regex("(\d)"), "<$1>")`, "a<1>b<2>"},
+		{`replace("a1b2", "1", "<$1>")`, "a<$1>b2"},
+		{`replace("(\d)", "(\d)", "$1")`, "$1"},
+		{`split("a, b,c ,  d", regex("\s*,\s*"))`, "[a, b, c, d]"},
+		{`split("a1b22c", regex("\d+"))`, "[a, b, c]"},
+		{`match("a", regex("("))`, "ValueError line 1: invalid regex: missing closing ): `(`"},
+		{`regex(1)`, "TypeError line 1: argument to `regex` must be STRING or REGEX, got=NUMBER"},
+		{`regex("[a")`, "ValueError line 1: invalid regex: missing closing ]: `[a`"},
+		{`match(1, "a")`, "TypeError line 1: first argument to `match` must be STRING, got=NUMBER"},
+		{`find_all("a", 1)`, "TypeError line 1: second argument to `find_all` must be STRING or REGEX, got=NUMBER"},
+		{`replace("a", 1, "b")`, "TypeError line 1: second argument to `replace` must be STRING or REGEX, got=NUMBER"},
+		{`regex()`, "ArityError line 1: wrong number of arguments. expected=1, got=0"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestRegexCache(t *testing.T) {
+	first, errObj := compileRegex(1, "a+")
+	if errObj != nil {
+		t.Fatalf("compileRegex returned an error: %s", errObj.Inspect())
+	}
+
+	if second, _ := compileRegex(1, "a+"); second != first {
+		t.Errorf("pattern was compiled again instead of being reused")
+	}
+
+	for i := 0; i < regexCacheSize*2; i++ {
+		compileRegex(1, fmt.Sprintf("p%d", i))
+	}
+
+	regexCache.Lock()
+	size := len(regexCache.patterns)
+	regexCache.Unlock()
+	if size > regexCacheSize {
+		t.Errorf("cache grew past its size. expected at most %d, got=%d", regexCacheSize, size)
+	}
+}
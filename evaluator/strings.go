@@ -0,0 +1,351 @@
+package evaluator
+
+import (
+	"github.com/butlermatt/monlox/object"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxStringLength is the most characters a builtin builds a string of from a count or width, so that a
+// mistaken one raises an error rather than exhausting memory.
+const maxStringLength = 1 << 24
+
+// stringBuiltins work with strings. Indexes, lengths and widths are counted in characters rather than bytes.
+var stringBuiltins = map[string]*object.Builtin{
+	// split splits a string around each occurrence of a separator, which is either a string or a regex.
+	"split": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 2, args); e != nil {
+				return e
+			}
+
+			str, errObj := stringArg(line, "first argument to `split`", args[0])
+			if errObj != nil {
+				return errObj
+			}
+
+			switch sep := args[1].(type) {
+			case *object.String:
+				// An empty separator splits between each character, as it does for strings.Split.
+				return stringsToArray(strings.Split(str, sep.Value))
+			case *object.Regex:
+				return stringsToArray(sep.Regexp.Split(str, -1))
+			}
+
+			return newArgumentError(line, "second argument to `split` must be STRING or REGEX, got=%s", args[1])
+		},
+	},
+	"join": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 2, args); e != nil {
+				return e
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newArgumentError(line, "first argument to `join` must be ARRAY, got=%s", args[0])
+			}
+			sep, errObj := stringArg(line, "second argument to `join`", args[1])
+			if errObj != nil {
+				return errObj
+			}
+
+			parts := make([]string, len(arr.Elements))
+			for i, el := range arr.Elements {
+				str, ok := el.(*object.String)
+				if !ok {
+					return newArgumentError(line, "elements joined by `join` must be STRING, got=%s", el)
+				}
+				parts[i] = str.Value
+			}
+
+			return &object.String{Value: strings.Join(parts, sep)}
+		},
+	},
+	// replace replaces each occurrence of a string or each match of a regex. In the replacement for a regex,
+	// $1 or ${name} stands for the text matched by a capture group, and $$ for a literal $. The replacement
+	// for a string is inserted as it is.
+	"replace": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 3, args); e != nil {
+				return e
+			}
+
+			str, errObj := stringArg(line, "first argument to `replace`", args[0])
+			if errObj != nil {
+				return errObj
+			}
+			repl, errObj := stringArg(line, "third argument to `replace`", args[2])
+			if errObj != nil {
+				return errObj
+			}
+
+			switch old := args[1].(type) {
+			case *object.String:
+				return &object.String{Value: strings.ReplaceAll(str, old.Value, repl)}
+			case *object.Regex:
+				return &object.String{Value: old.Regexp.ReplaceAllString(str, repl)}
+			}
+
+			return newArgumentError(line, "second argument to `replace` must be STRING or REGEX, got=%s", args[1])
+		},
+	},
+	"contains": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 2, args); e != nil {
+				return e
+			}
+
+			str, sub, errObj := twoStringArgs(line, "contains", args)
+			if errObj != nil {
+				return errObj
+			}
+
+			return nativeBooltoObject(strings.Contains(str, sub))
+		},
+	},
+	"starts_with": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 2, args); e != nil {
+				return e
+			}
+
+			str, prefix, errObj := twoStringArgs(line, "starts_with", args)
+			if errObj != nil {
+				return errObj
+			}
+
+			return nativeBooltoObject(strings.HasPrefix(str, prefix))
+		},
+	},
+	"ends_with": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 2, args); e != nil {
+				return e
+			}
+
+			str, suffix, errObj := twoStringArgs(line, "ends_with", args)
+			if errObj != nil {
+				return errObj
+			}
+
+			return nativeBooltoObject(strings.HasSuffix(str, suffix))
+		},
+	},
+	"index_of": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 2, args); e != nil {
+				return e
+			}
+
+			str, sub, errObj := twoStringArgs(line, "index_of", args)
+			if errObj != nil {
+				return errObj
+			}
+
+			i := strings.Index(str, sub)
+			if i < 0 {
+				return &object.Number{Value: -1}
+			}
+
+			return &object.Number{Value: float32(utf8.RuneCountInString(str[:i]))}
+		},
+	},
+	"upper": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			str, errObj := stringArg(line, "argument to `upper`", args[0])
+			if errObj != nil {
+				return errObj
+			}
+
+			return &object.String{Value: strings.ToUpper(str)}
+		},
+	},
+	"lower": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			str, errObj := stringArg(line, "argument to `lower`", args[0])
+			if errObj != nil {
+				return errObj
+			}
+
+			return &object.String{Value: strings.ToLower(str)}
+		},
+	},
+	"trim": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectArgsBetween(line, 1, 2, args); e != nil {
+				return e
+			}
+
+			str, errObj := stringArg(line, "first argument to `trim`", args[0])
+			if errObj != nil {
+				return errObj
+			}
+
+			if len(args) == 1 {
+				return &object.String{Value: strings.TrimSpace(str)}
+			}
+
+			cutset, errObj := stringArg(line, "second argument to `trim`", args[1])
+			if errObj != nil {
+				return errObj
+			}
+
+			return &object.String{Value: strings.Trim(str, cutset)}
+		},
+	},
+	"repeat": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 2, args); e != nil {
+				return e
+			}
+
+			str, errObj := stringArg(line, "first argument to `repeat`", args[0])
+			if errObj != nil {
+				return errObj
+			}
+			count, errObj := intArg(line, "second argument to `repeat`", args[1])
+			if errObj != nil {
+				return errObj
+			}
+			if count < 0 {
+				return newError(line, object.ValueError, "`repeat` count must not be negative, got=%d", count)
+			}
+			if n := utf8.RuneCountInString(str); count > 0 && n > maxStringLength/count {
+				return newError(line, object.ValueError, "`repeat` result would be longer than %d characters", maxStringLength)
+			}
+
+			return &object.String{Value: strings.Repeat(str, count)}
+		},
+	},
+	"pad": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectArgsBetween(line, 2, 3, args); e != nil {
+				return e
+			}
+
+			str, errObj := stringArg(line, "first argument to `pad`", args[0])
+			if errObj != nil {
+				return errObj
+			}
+			width, errObj := intArg(line, "second argument to `pad`", args[1])
+			if errObj != nil {
+				return errObj
+			}
+			if width > maxStringLength || width < -maxStringLength {
+				return newError(line, object.ValueError, "`pad` width must be at most %d characters, got=%d", maxStringLength, width)
+			}
+
+			fill := " "
+			if len(args) == 3 {
+				if fill, errObj = stringArg(line, "third argument to `pad`", args[2]); errObj != nil {
+					return errObj
+				}
+				if utf8.RuneCountInString(fill) != 1 {
+					return newError(line, object.ValueError, "`pad` fill must be a single character, got=%q", fill)
+				}
+			}
+
+			return &object.String{Value: pad(str, width, fill)}
+		},
+	},
+	"chars": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			str, errObj := stringArg(line, "argument to `chars`", args[0])
+			if errObj != nil {
+				return errObj
+			}
+
+			return stringsToArray(strings.Split(str, ""))
+		},
+	},
+}
+
+// pad pads str with fill to width characters. As with printf, a positive width right aligns str and a
+// negative width left aligns it.
+func pad(str string, width int, fill string) string {
+	left := width > 0
+	if !left {
+		width = -width
+	}
+
+	n := width - utf8.RuneCountInString(str)
+	if n <= 0 {
+		return str
+	}
+
+	if left {
+		return strings.Repeat(fill, n) + str
+	}
+	return str + strings.Repeat(fill, n)
+}
+
+func stringsToArray(strs []string) *object.Array {
+	elements := make([]object.Object, len(strs))
+	for i, str := range strs {
+		elements[i] = &object.String{Value: str}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// stringArg returns the value of arg, or a TypeError if it is not a string. desc describes the argument in
+// the error.
+func stringArg(line int, desc string, arg object.Object) (string, *object.Error) {
+	str, ok := arg.(*object.String)
+	if !ok {
+		return "", newArgumentError(line, desc+" must be STRING, got=%s", arg)
+	}
+
+	return str.Value, nil
+}
+
+// twoStringArgs returns the values of the two string arguments to the builtin name.
+func twoStringArgs(line int, name string, args []object.Object) (string, string, *object.Error) {
+	first, errObj := stringArg(line, "first argument to `"+name+"`", args[0])
+	if errObj != nil {
+		return "", "", errObj
+	}
+
+	second, errObj := stringArg(line, "second argument to `"+name+"`", args[1])
+	if errObj != nil {
+		return "", "", errObj
+	}
+
+	return first, second, nil
+}
+
+// intArg returns the value of arg, or an error if it is not a whole number. desc describes the argument in
+// the error.
+func intArg(line int, desc string, arg object.Object) (int, *object.Error) {
+	num, ok := arg.(*object.Number)
+	if !ok {
+		return 0, newArgumentError(line, desc+" must be NUMBER, got=%s", arg)
+	}
+
+	if num.Value != float32(math.Trunc(float64(num.Value))) {
+		errObj := newError(line, object.ValueError, "%s must be a whole number, got=%v", desc, num.Value)
+		errObj.Operands = []object.Object{arg}
+		return 0, errObj
+	}
+	if num.Value > math.MaxInt32 || num.Value < math.MinInt32 {
+		errObj := newError(line, object.ValueError, "%s is out of range, got=%v", desc, num.Value)
+		errObj.Operands = []object.Object{arg}
+		return 0, errObj
+	}
+
+	return int(num.Value), nil
+}
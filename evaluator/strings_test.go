@@ -0,0 +1,61 @@
+package evaluator
+
+import "testing"
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`split("a,b,c", ",")`, "[a, b, c]"},
+		{`split("a,b,", ",")`, "[a, b, ]"},
+		{`split("héllo", "")`, "[h, é, l, l, o]"},
+		{`split("", ",")`, "[]"},
+		{`join(["a", "b", "c"], "-")`, "a-b-c"},
+		{`join([], "-")`, ""},
+		{`join(split("a b", " "), "")`, "ab"},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`replace("aaa", "a", "")`, ""},
+		{`contains("hello", "ell")`, "true"},
+		{`contains("hello", "z")`, "false"},
+		{`starts_with("hello", "he")`, "true"},
+		{`starts_with("hello", "lo")`, "false"},
+		{`ends_with("hello", "lo")`, "true"},
+		{`index_of("hello", "l")`, "2"},
+		{`index_of("héllo", "l")`, "2"},
+		{`index_of("hello", "z")`, "-1"},
+		{`upper("héllo")`, "HÉLLO"},
+		{`lower("HÉLLO")`, "héllo"},
+		{"trim(\"  hi\n \")", "hi"},
+		{`trim("xxhixx", "x")`, "hi"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", 0)`, ""},
+		{`pad("ab", 5)`, "   ab"},
+		{`pad("ab", -5)`, "ab   "},
+		{`pad("é", 3, "0")`, "00é"},
+		{`pad("abcdef", 3)`, "abcdef"},
+		{`pad("ab", 4, "·")`, "··ab"},
+		{`chars("hé")`, "[h, é]"},
+		{`chars("")`, "[]"},
+		{`split(1, ",")`, "TypeError line 1: first argument to `split` must be STRING, got=NUMBER"},
+		{`split("a", 1)`, "TypeError line 1: second argument to `split` must be STRING or REGEX, got=NUMBER"},
+		{`join("a", "")`, "TypeError line 1: first argument to `join` must be ARRAY, got=STRING"},
+		{`join(["a", 1], "")`, "TypeError line 1: elements joined by `join` must be STRING, got=NUMBER"},
+		{`replace("a", "b", 1)`, "TypeError line 1: third argument to `replace` must be STRING, got=NUMBER"},
+		{`upper("a", "b")`, "ArityError line 1: wrong number of arguments. expected=1, got=2"},
+		{`trim("a", "b", "c")`, "ArityError line 1: wrong number of arguments. expected=1 to 2, got=3"},
+		{`repeat("a", -1)`, "ValueError line 1: `repeat` count must not be negative, got=-1"},
+		{`repeat("a", 1.5)`, "ValueError line 1: second argument to `repeat` must be a whole number, got=1.5"},
+		{`repeat("ab", 10000000)`, "ValueError line 1: `repeat` result would be longer than 16777216 characters"},
+		{`repeat("a", 100000 * 100000)`, "ValueError line 1: second argument to `repeat` is out of range, got=1e+10"},
+		{`pad("a", -20000000)`, "ValueError line 1: `pad` width must be at most 16777216 characters, got=-20000000"},
+		{`pad("a", 3, "ab")`, `ValueError line 1: ` + "`pad`" + ` fill must be a single character, got="ab"`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
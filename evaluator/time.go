@@ -0,0 +1,436 @@
+package evaluator
+
+import (
+	"context"
+	"fmt"
+	"github.com/butlermatt/monlox/ast"
+	"github.com/butlermatt/monlox/object"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the names of common layouts, which format_time and parse_time accept in place of a
+// layout.
+var timeLayouts = map[string]string{
+	"RFC3339":  time.RFC3339,
+	"RFC1123":  time.RFC1123,
+	"DateTime": time.DateTime,
+	"DateOnly": time.DateOnly,
+	"TimeOnly": time.TimeOnly,
+	"Kitchen":  time.Kitchen,
+}
+
+// timeBuiltins work with times and durations, which may be added, subtracted and compared with the usual
+// operators. The current time comes from the Clock of the Caller. Layouts are those of Go's time package,
+// such as "2006-01-02 15:04", or the name of one in timeLayouts.
+var timeBuiltins = map[string]*object.Builtin{
+	"now": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 0, args); e != nil {
+				return e
+			}
+
+			cc, ok := c.(ClockCaller)
+			if !ok {
+				return newUnsupportedError(line, "now")
+			}
+
+			return &object.Time{Value: cc.TimeSource().Now()}
+		},
+	},
+	// unix returns the seconds since the Unix epoch of a time, or of now, as a string of decimal digits such
+	// as "1715938200.5". A Number only holds about seven digits, too few for recent times, so a string keeps
+	// the timestamp exact; subtract times to measure durations.
+	"unix": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectArgsBetween(line, 0, 1, args); e != nil {
+				return e
+			}
+
+			var t time.Time
+			if len(args) == 1 {
+				arg, ok := args[0].(*object.Time)
+				if !ok {
+					return newArgumentError(line, "argument to `unix` must be TIME, got=%s", args[0])
+				}
+				t = arg.Value
+			} else if cc, ok := c.(ClockCaller); ok {
+				t = cc.TimeSource().Now()
+			} else {
+				return newUnsupportedError(line, "unix")
+			}
+
+			return &object.String{Value: formatUnix(t)}
+		},
+	},
+	// from_unix returns the time a number of seconds after the Unix epoch, in UTC. The seconds are a Number
+	// or a string as returned by unix.
+	"from_unix": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			switch arg := args[0].(type) {
+			case *object.String:
+				t, ok := parseUnix(arg.Value)
+				if !ok {
+					errObj := newError(line, object.ValueError, "cannot parse %q as seconds since the Unix epoch", arg.Value)
+					errObj.Operands = []object.Object{arg}
+					return errObj
+				}
+				return &object.Time{Value: t.UTC()}
+			case *object.Number:
+				d, errObj := secondsToDuration(line, arg.Value)
+				if errObj != nil {
+					return errObj
+				}
+				return &object.Time{Value: time.Unix(0, int64(d)).UTC()}
+			}
+
+			return newArgumentError(line, "argument to `from_unix` must be NUMBER or STRING, got=%s", args[0])
+		},
+	},
+	// sleep waits for a duration, or a number of seconds, to pass. It returns early with an error if the
+	// evaluation is cancelled, which it cannot be if the Caller provides no context.
+	"sleep": {
+		Fn: func(c object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			d, errObj := durationArg(line, "argument to `sleep`", args[0])
+			if errObj != nil {
+				return errObj
+			}
+			if d < 0 {
+				return newError(line, object.ValueError, "`sleep` duration must not be negative, got=%s", d)
+			}
+
+			cc, ok := c.(ClockCaller)
+			if !ok {
+				return newUnsupportedError(line, "sleep")
+			}
+
+			ctx := context.Background()
+			if xc, ok := c.(ContextCaller); ok {
+				ctx = xc.Context()
+			}
+
+			cc.TimeSource().Sleep(ctx, d)
+			if errObj := contextError(line, ctx); errObj != nil {
+				return errObj
+			}
+
+			return Null
+		},
+	},
+	// duration returns a duration given as a number of seconds or as a string such as "1h30m".
+	"duration": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			switch arg := args[0].(type) {
+			case *object.String:
+				d, err := time.ParseDuration(arg.Value)
+				if err != nil {
+					return newError(line, object.ValueError, "invalid duration: %s", strings.TrimPrefix(err.Error(), "time: "))
+				}
+				return &object.Duration{Value: d}
+			case *object.Number:
+				d, errObj := secondsToDuration(line, arg.Value)
+				if errObj != nil {
+					return errObj
+				}
+				return &object.Duration{Value: d}
+			case *object.Duration:
+				return arg
+			}
+
+			return newArgumentError(line, "argument to `duration` must be NUMBER or STRING, got=%s", args[0])
+		},
+	},
+	// seconds returns the length of a duration in seconds, rounded to the nearest Number.
+	"seconds": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			d, ok := args[0].(*object.Duration)
+			if !ok {
+				return newArgumentError(line, "argument to `seconds` must be DURATION, got=%s", args[0])
+			}
+
+			secs := float32(d.Value.Seconds())
+			if math.IsInf(float64(secs), 0) {
+				errObj := newError(line, object.ValueError, "%s is too long to be a NUMBER of seconds", d.Value)
+				errObj.Operands = []object.Object{d}
+				return errObj
+			}
+
+			return &object.Number{Value: secs}
+		},
+	},
+	// format_time returns a time formatted by a layout, RFC3339 by default.
+	"format_time": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectArgsBetween(line, 1, 2, args); e != nil {
+				return e
+			}
+
+			t, ok := args[0].(*object.Time)
+			if !ok {
+				return newArgumentError(line, "first argument to `format_time` must be TIME, got=%s", args[0])
+			}
+
+			layout, errObj := layoutArg(line, "format_time", args)
+			if errObj != nil {
+				return errObj
+			}
+
+			return &object.String{Value: t.Value.Format(layout)}
+		},
+	},
+	// parse_time returns the time written in a string laid out by a layout, RFC3339 by default. Times without
+	// a time zone are taken to be in UTC.
+	"parse_time": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectArgsBetween(line, 1, 2, args); e != nil {
+				return e
+			}
+
+			str, errObj := stringArg(line, "first argument to `parse_time`", args[0])
+			if errObj != nil {
+				return errObj
+			}
+
+			layout, errObj := layoutArg(line, "parse_time", args)
+			if errObj != nil {
+				return errObj
+			}
+
+			t, err := time.Parse(layout, str)
+			if err != nil {
+				errObj := newError(line, object.ValueError, "cannot parse %q as a time with layout %q", str, layout)
+				errObj.Operands = []object.Object{args[0]}
+				return errObj
+			}
+
+			return &object.Time{Value: t}
+		},
+	},
+}
+
+// evalTimeInfixExpression evaluates arithmetic and comparisons on times and durations. It returns nil if
+// neither operand is a time or duration, or the operator does not apply to them, so they are evaluated as
+// they are for any other type.
+func evalTimeInfixExpression(infix *ast.InfixExpression, left, right object.Object) object.Object {
+	switch l := left.(type) {
+	case *object.Time:
+		switch r := right.(type) {
+		case *object.Duration:
+			switch infix.Operator {
+			case "+":
+				return &object.Time{Value: l.Value.Add(r.Value)}
+			case "-":
+				return &object.Time{Value: l.Value.Add(-r.Value)}
+			}
+		case *object.Time:
+			switch infix.Operator {
+			case "-":
+				return &object.Duration{Value: l.Value.Sub(r.Value)}
+			case "<":
+				return nativeBooltoObject(l.Value.Before(r.Value))
+			case ">":
+				return nativeBooltoObject(l.Value.After(r.Value))
+			case "<=":
+				return nativeBooltoObject(!l.Value.After(r.Value))
+			case ">=":
+				return nativeBooltoObject(!l.Value.Before(r.Value))
+			}
+		}
+	case *object.Duration:
+		switch r := right.(type) {
+		case *object.Time:
+			if infix.Operator == "+" {
+				return &object.Time{Value: r.Value.Add(l.Value)}
+			}
+		case *object.Duration:
+			switch infix.Operator {
+			case "+":
+				sum := l.Value + r.Value
+				if (r.Value > 0) != (sum > l.Value) && r.Value != 0 {
+					return newDurationRangeError(infix)
+				}
+				return &object.Duration{Value: sum}
+			case "-":
+				diff := l.Value - r.Value
+				if (r.Value > 0) != (diff < l.Value) && r.Value != 0 {
+					return newDurationRangeError(infix)
+				}
+				return &object.Duration{Value: diff}
+			case "/":
+				if r.Value == 0 {
+					return newDivisionByZeroError(infix)
+				}
+				return &object.Number{Value: float32(float64(l.Value) / float64(r.Value))}
+			case "<":
+				return nativeBooltoObject(l.Value < r.Value)
+			case ">":
+				return nativeBooltoObject(l.Value > r.Value)
+			case "<=":
+				return nativeBooltoObject(l.Value <= r.Value)
+			case ">=":
+				return nativeBooltoObject(l.Value >= r.Value)
+			}
+		case *object.Number:
+			switch infix.Operator {
+			case "*":
+				return scaleDuration(infix, l.Value, float64(r.Value))
+			case "/":
+				if r.Value == 0 {
+					return newDivisionByZeroError(infix)
+				}
+				return scaleDuration(infix, l.Value, 1/float64(r.Value))
+			}
+		}
+	case *object.Number:
+		if r, ok := right.(*object.Duration); ok && infix.Operator == "*" {
+			return scaleDuration(infix, r.Value, float64(l.Value))
+		}
+	}
+
+	return nil
+}
+
+// scaleDuration returns d multiplied by factor for infix, or a ValueError if the result is too long to be
+// a duration.
+func scaleDuration(infix *ast.InfixExpression, d time.Duration, factor float64) object.Object {
+	scaled := math.Round(float64(d) * factor)
+	if math.IsNaN(scaled) || scaled < math.MinInt64 || scaled >= math.MaxInt64 {
+		return newDurationRangeError(infix)
+	}
+
+	return &object.Duration{Value: time.Duration(scaled)}
+}
+
+// newDurationRangeError returns the ValueError for infix giving a duration too long to be held.
+func newDurationRangeError(infix *ast.InfixExpression) *object.Error {
+	errObj := newError(infix.Token.Line, object.ValueError, "result of `%s` is out of range for a DURATION", infix.Operator)
+	errObj.Column = infix.Token.Column
+	return errObj
+}
+
+// newDivisionByZeroError returns the ValueError for infix dividing a duration by a zero duration or number.
+func newDivisionByZeroError(infix *ast.InfixExpression) *object.Error {
+	errObj := newError(infix.Token.Line, object.ValueError, "division of DURATION by zero")
+	errObj.Column = infix.Token.Column
+	return errObj
+}
+
+// formatUnix returns the seconds since the Unix epoch of t in decimal, without trailing zeros.
+func formatUnix(t time.Time) string {
+	sec, nsec := t.Unix(), t.Nanosecond()
+
+	sign := ""
+	if sec < 0 {
+		// Times before the epoch are a whole number of seconds before it less nsec, so count back from the
+		// next second instead.
+		sign, sec = "-", -sec
+		if nsec > 0 {
+			sec, nsec = sec-1, int(time.Second)-nsec
+		}
+	}
+
+	str := sign + strconv.FormatInt(sec, 10)
+	if nsec > 0 {
+		str += strings.TrimRight(fmt.Sprintf(".%09d", nsec), "0")
+	}
+
+	return str
+}
+
+// parseUnix returns the time str seconds after the Unix epoch, where str is written in decimal with at most
+// nine decimals, as returned by formatUnix. ok is false if str is not of that form.
+func parseUnix(str string) (t time.Time, ok bool) {
+	neg := strings.HasPrefix(str, "-")
+	whole, frac, hasFrac := strings.Cut(strings.TrimPrefix(str, "-"), ".")
+	if !isDigits(whole) || hasFrac && (!isDigits(frac) || len(frac) > 9) {
+		return time.Time{}, false
+	}
+
+	sec, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	nsec, _ := strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+
+	if neg {
+		sec, nsec = -sec, -nsec
+	}
+
+	return time.Unix(sec, nsec), true
+}
+
+// isDigits reports whether str is made of one or more decimal digits.
+func isDigits(str string) bool {
+	if str == "" {
+		return false
+	}
+
+	for _, ch := range str {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// durationArg returns arg, which is a duration or a number of seconds, as a time.Duration. desc describes
+// the argument in the error.
+func durationArg(line int, desc string, arg object.Object) (time.Duration, *object.Error) {
+	switch arg := arg.(type) {
+	case *object.Duration:
+		return arg.Value, nil
+	case *object.Number:
+		return secondsToDuration(line, arg.Value)
+	}
+
+	return 0, newArgumentError(line, desc+" must be DURATION or NUMBER, got=%s", arg)
+}
+
+// secondsToDuration returns the duration of secs seconds. secs is taken to be the shortest decimal which
+// is displayed for it, so that duration(1.1) is exactly 1.1s rather than the nearest NUMBER to it.
+func secondsToDuration(line int, secs float32) (time.Duration, *object.Error) {
+	d, err := time.ParseDuration(strconv.FormatFloat(float64(secs), 'f', -1, 32) + "s")
+	if err != nil {
+		return 0, newError(line, object.ValueError, "%v seconds is out of range for a DURATION", secs)
+	}
+
+	return d, nil
+}
+
+// layoutArg returns the layout given as the optional second argument to the builtin name, resolving the
+// names in timeLayouts. The layout defaults to RFC3339.
+func layoutArg(line int, name string, args []object.Object) (string, *object.Error) {
+	if len(args) < 2 {
+		return time.RFC3339, nil
+	}
+
+	layout, errObj := stringArg(line, "second argument to `"+name+"`", args[1])
+	if errObj != nil {
+		return "", errObj
+	}
+
+	if named, ok := timeLayouts[layout]; ok {
+		return named, nil
+	}
+
+	return layout, nil
+}
@@ -0,0 +1,152 @@
+package evaluator
+
+import (
+	"context"
+	"github.com/butlermatt/monlox/lexer"
+	"github.com/butlermatt/monlox/object"
+	"github.com/butlermatt/monlox/parser"
+	"testing"
+	"time"
+)
+
+// testStart is the time of the clock used by testEvalClock.
+var testStart = time.Date(2024, 5, 17, 9, 30, 0, 0, time.UTC)
+
+func testEvalClock(input string) object.Object {
+	e := New()
+	e.Clock = NewManualClock(testStart)
+
+	return e.Eval(parser.New(lexer.New(input)).ParseProgram(), object.NewEnvironment())
+}
+
+func TestTimeBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"now()", "2024-05-17T09:30:00Z"},
+		{"now() == now()", "true"},
+		{"type(now())", "TIME"},
+		{`type(duration("1s"))`, "DURATION"},
+		{"is_time(now())", "true"},
+		{"is_duration(now())", "false"},
+		{"unix()", "1715938200"},
+		{"unix(from_unix(86400))", "86400"},
+		{`let t = now() + duration("123456789ns"); from_unix(unix(t)) == t`, "true"},
+		{`unix(now() + duration("1.5s"))`, "1715938201.5"},
+		{`unix(from_unix("-1.25"))`, "-1.25"},
+		{`unix(from_unix("-2"))`, "-2"},
+		{`from_unix("1715938200.000000001")`, "2024-05-17T09:30:00.000000001Z"},
+		{`from_unix("-0.5")`, "1969-12-31T23:59:59.5Z"},
+		{"from_unix(0)", "1970-01-01T00:00:00Z"},
+		{"from_unix(1.5)", "1970-01-01T00:00:01.5Z"},
+		{"from_unix(0.1)", "1970-01-01T00:00:00.1Z"},
+		{`duration("1h30m")`, "1h30m0s"},
+		{"duration(90)", "1m30s"},
+		{"duration(0.25)", "250ms"},
+		{"seconds(duration(\"2m\"))", "120"},
+		{`seconds(duration("1.1s"))`, "1.1"},
+		{`seconds(duration("-1ms"))`, "-0.001"},
+		{`duration(1.1) == duration("1.1s")`, "true"},
+		{`seconds(duration("1h0m0.000000001s"))`, "3600"},
+		{`seconds(duration("50.156855ms"))`, "0.050156854"},
+		{`let t = now(); sleep(0.05); seconds(now() - t)`, "0.05"},
+		{`let t = now(); sleep(duration("1h")); now() - t`, "1h0m0s"},
+		{"let t = now(); sleep(1.5); now() - t", "1.5s"},
+		{`now() + duration("36h")`, "2024-05-18T21:30:00Z"},
+		{`duration("1h") + now()`, "2024-05-17T10:30:00Z"},
+		{`now() - duration("30m")`, "2024-05-17T09:00:00Z"},
+		{`duration("1h") + duration("15m")`, "1h15m0s"},
+		{`duration("1h") - duration("2h")`, "-1h0m0s"},
+		{`-duration("1m")`, "-1m0s"},
+		{`duration("1m") * 3`, "3m0s"},
+		{`2 * duration("1m")`, "2m0s"},
+		{`duration("1m") / 4`, "15s"},
+		{`duration("1h") / duration("20m")`, "3"},
+		{`duration("1m") < duration("1h")`, "true"},
+		{`duration("1m") >= duration("1h")`, "false"},
+		{`duration("60s") == duration("1m")`, "true"},
+		{`now() < now() + duration("1s")`, "true"},
+		{`now() >= now()`, "true"},
+		{`now() > now()`, "false"},
+		{`format_time(now(), "2006-01-02 15:04")`, "2024-05-17 09:30"},
+		{`format_time(now(), "DateOnly")`, "2024-05-17"},
+		{`format_time(now(), "Kitchen")`, "9:30AM"},
+		{`format_time(now())`, "2024-05-17T09:30:00Z"},
+		{`parse_time("2024-05-17T09:30:00Z") == now()`, "true"},
+		{`parse_time("17/05/2024", "02/01/2006")`, "2024-05-17T00:00:00Z"},
+		{`parse_time("2024-05-17 11:30:00+02:00", "2006-01-02 15:04:05-07:00") == now()`, "true"},
+		{`now() - parse_time("2024-05-16", "DateOnly")`, "33h30m0s"},
+		{`parse_time("soon")`, "ValueError line 1: cannot parse \"soon\" as a time with layout \"2006-01-02T15:04:05Z07:00\""},
+		{`duration("soon")`, "ValueError line 1: invalid duration: invalid duration \"soon\""},
+		{`duration("1m") / duration("0s")`, "ValueError line 1: division of DURATION by zero"},
+		{`duration("1m") / 0`, "ValueError line 1: division of DURATION by zero"},
+		{`duration("2562047h") + duration("2562047h")`, "ValueError line 1: result of `+` is out of range for a DURATION"},
+		{`duration("-2562047h") - duration("2562047h")`, "ValueError line 1: result of `-` is out of range for a DURATION"},
+		{`duration("-2562047h") + duration("-2562047h")`, "ValueError line 1: result of `+` is out of range for a DURATION"},
+		{`duration("2562047h") - duration("-2562047h")`, "ValueError line 1: result of `-` is out of range for a DURATION"},
+		{`duration("2562047h") - duration("2562047h")`, "0s"},
+		{`duration("2562047h") + duration("-2562047h")`, "0s"},
+		{`duration("1h") * 100000 * 100000`, "ValueError line 1: result of `*` is out of range for a DURATION"},
+		{`from_unix("1.5s")`, "ValueError line 1: cannot parse \"1.5s\" as seconds since the Unix epoch"},
+		{`from_unix("1.")`, "ValueError line 1: cannot parse \"1.\" as seconds since the Unix epoch"},
+		{`from_unix("0.0000000001")`, "ValueError line 1: cannot parse \"0.0000000001\" as seconds since the Unix epoch"},
+		{"from_unix(now())", "TypeError line 1: argument to `from_unix` must be NUMBER or STRING, got=TIME"},
+		{"duration(1 / 0)", "ValueError line 1: +Inf seconds is out of range for a DURATION"},
+		{"duration(100000 * 100000 * 100000)", "ValueError line 1: 1e+15 seconds is out of range for a DURATION"},
+		{"sleep(-1)", "ValueError line 1: `sleep` duration must not be negative, got=-1s"},
+		{`sleep("1s")`, "TypeError line 1: argument to `sleep` must be DURATION or NUMBER, got=STRING"},
+		{"duration(true)", "TypeError line 1: argument to `duration` must be NUMBER or STRING, got=BOOLEAN"},
+		{"format_time(1)", "TypeError line 1: first argument to `format_time` must be TIME, got=NUMBER"},
+		{"now() + 1", "TypeError line 1: type mismatch: TIME + NUMBER"},
+		{"now() + now()", "TypeError line 1: unknown operator: TIME + TIME"},
+		{"now(1)", "ArityError line 1: wrong number of arguments. expected=0, got=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalClock(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestSleepCancelled(t *testing.T) {
+	program := parser.New(lexer.New("sleep(60)")).ParseProgram()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	evaluated := New().EvalContext(ctx, program, object.NewEnvironment())
+
+	expected := "TimeoutError line 1: execution timed out"
+	if evaluated.Inspect() != expected {
+		t.Errorf("wrong result. expected=%q, got=%q", expected, evaluated.Inspect())
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("sleep was not interrupted. took %s", elapsed)
+	}
+}
+
+func TestManualClock(t *testing.T) {
+	clock := NewManualClock(testStart)
+
+	clock.Advance(time.Minute)
+	if got := clock.Now(); !got.Equal(testStart.Add(time.Minute)) {
+		t.Errorf("wrong time after Advance. got=%s", got)
+	}
+
+	clock.Set(testStart)
+	if got := clock.Now(); !got.Equal(testStart) {
+		t.Errorf("wrong time after Set. got=%s", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	clock.Sleep(ctx, time.Hour)
+	if got := clock.Now(); !got.Equal(testStart) {
+		t.Errorf("cancelled Sleep advanced the clock. got=%s", got)
+	}
+}
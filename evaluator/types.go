@@ -0,0 +1,122 @@
+package evaluator
+
+import (
+	"github.com/butlermatt/monlox/object"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// typeBuiltins inspect the types of values and convert values between types.
+var typeBuiltins = map[string]*object.Builtin{
+	"type": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			return &object.String{Value: args[0].Type().String()}
+		},
+	},
+	"str": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			if str, ok := args[0].(*object.String); ok {
+				return str
+			}
+
+			return &object.String{Value: args[0].Inspect()}
+		},
+	},
+	"num":   {Fn: toNumber("num", false)},
+	"float": {Fn: toNumber("float", false)},
+	"int":   {Fn: toNumber("int", true)},
+	"bool": {
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			return nativeBooltoObject(isTruthy(args[0]))
+		},
+	},
+	"is_null":     typePredicate(object.NULL),
+	"is_number":   typePredicate(object.NUMBER),
+	"is_bool":     typePredicate(object.BOOLEAN),
+	"is_string":   typePredicate(object.STRING),
+	"is_array":    typePredicate(object.ARRAY),
+	"is_hash":     typePredicate(object.HASH),
+	"is_set":      typePredicate(object.SET),
+	"is_regex":    typePredicate(object.REGEX),
+	"is_time":     typePredicate(object.TIME),
+	"is_duration": typePredicate(object.DURATION),
+	"is_function": typePredicate(object.FUNCTION, object.BUILTIN),
+}
+
+// toNumber returns the implementation of a builtin converting numbers, booleans and strings holding a
+// number to a Number. If whole is set the number is truncated towards zero, and strings must hold a whole
+// number.
+func toNumber(name string, whole bool) object.BuiltinFunction {
+	return func(_ object.Caller, line int, args ...object.Object) object.Object {
+		if e := expectNArgs(line, 1, args); e != nil {
+			return e
+		}
+
+		var value float64
+		switch arg := args[0].(type) {
+		case *object.Number:
+			value = float64(arg.Value)
+		case *object.Boolean:
+			if arg.Value {
+				value = 1
+			}
+		case *object.String:
+			text := strings.TrimSpace(arg.Value)
+
+			var err error
+			if whole {
+				var n int64
+				n, err = strconv.ParseInt(text, 10, 64)
+				value = float64(n)
+			} else {
+				value, err = strconv.ParseFloat(text, 32)
+			}
+
+			if err != nil {
+				errObj := newError(line, object.ValueError, "cannot convert %q to NUMBER with `%s`", arg.Value, name)
+				errObj.Operands = []object.Object{arg}
+				return errObj
+			}
+		default:
+			return newArgumentError(line, "argument to `"+name+"` must be NUMBER, BOOLEAN or STRING, got=%s", arg)
+		}
+
+		if whole {
+			value = math.Trunc(value)
+		}
+
+		return &object.Number{Value: float32(value)}
+	}
+}
+
+// typePredicate returns a builtin reporting whether its argument is one of types.
+func typePredicate(types ...object.Type) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(_ object.Caller, line int, args ...object.Object) object.Object {
+			if e := expectNArgs(line, 1, args); e != nil {
+				return e
+			}
+
+			for _, t := range types {
+				if args[0].Type() == t {
+					return True
+				}
+			}
+
+			return False
+		},
+	}
+}
@@ -0,0 +1,66 @@
+package evaluator
+
+import "testing"
+
+func TestTypeBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"type(1)", "NUMBER"},
+		{`type("a")`, "STRING"},
+		{"type(true)", "BOOLEAN"},
+		{"type(if (false) { 1 })", "NULL"},
+		{"type([])", "ARRAY"},
+		{"type({})", "HASH"},
+		{"type(#{})", "SET"},
+		{"type(fn() {})", "FUNCTION"},
+		{"type(len)", "BUILTIN"},
+		{"str(1.5)", "1.5"},
+		{`str("a")`, "a"},
+		{`str([1, "a"]) + "!"`, "[1, a]!"},
+		{`str(true) == "true"`, "true"},
+		{`num("5") + 1`, "6"},
+		{`num(" -2.5 ")`, "-2.5"},
+		{`num("1e3")`, "1000"},
+		{"num(true)", "1"},
+		{"num(false)", "0"},
+		{"num(7)", "7"},
+		{`float("0.25")`, "0.25"},
+		{`int("42")`, "42"},
+		{"int(3.7)", "3"},
+		{"int(-3.7)", "-3"},
+		{"int(true)", "1"},
+		{"bool(0)", "true"},
+		{`bool("")`, "true"},
+		{"bool(false)", "false"},
+		{"bool(if (false) { 1 })", "false"},
+		{"is_number(1)", "true"},
+		{`is_number("1")`, "false"},
+		{`is_string("1")`, "true"},
+		{"is_bool(false)", "true"},
+		{"is_null(if (false) { 1 })", "true"},
+		{"is_null(0)", "false"},
+		{"is_array([])", "true"},
+		{"is_hash({})", "true"},
+		{"is_set(#{})", "true"},
+		{"is_set([])", "false"},
+		{"is_function(fn() {})", "true"},
+		{"is_function(len)", "true"},
+		{"is_function(1)", "false"},
+		{`num("abc")`, "ValueError line 1: cannot convert \"abc\" to NUMBER with `num`"},
+		{`num("")`, "ValueError line 1: cannot convert \"\" to NUMBER with `num`"},
+		{`int("3.5")`, "ValueError line 1: cannot convert \"3.5\" to NUMBER with `int`"},
+		{"num([1])", "TypeError line 1: argument to `num` must be NUMBER, BOOLEAN or STRING, got=ARRAY"},
+		{"type()", "ArityError line 1: wrong number of arguments. expected=1, got=0"},
+		{"is_number(1, 2)", "ArityError line 1: wrong number of arguments. expected=1, got=2"},
+		{`try { num("x") } catch (e) { e["kind"] }`, "ValueError"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
@@ -9,6 +9,7 @@ type Lexer struct {
 	readPosition int  // current reading position in input (after current char)
 	ch           byte // current character
 	line         int  // current line
+	lineStart    int  // position in the input of the start of the current line
 }
 
 func newToken(tokenType token.TokenType, ch byte, line int) token.Token {
@@ -29,7 +30,13 @@ func isAlphaNumeric(ch byte) bool {
 
 // New returns a new Lexer populated with the specified input program.
 func New(input string) *Lexer {
-	l := &Lexer{input: input, line: 1}
+	return NewAt(input, 1)
+}
+
+// NewAt returns a new Lexer for input which starts on the given line of a larger source, such as an entry
+// in a REPL session, so that its tokens are numbered by their line in that source.
+func NewAt(input string, line int) *Lexer {
+	l := &Lexer{input: input, line: line}
 	l.readChar()
 	return l
 }
@@ -80,6 +87,9 @@ func (l *Lexer) readString() (string, bool) {
 			ok = false
 			break
 		}
+		if l.ch == '\n' {
+			l.newLine()
+		}
 	}
 	return l.input[pos:l.position], ok
 }
@@ -87,12 +97,18 @@ func (l *Lexer) readString() (string, bool) {
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
 		if l.ch == '\n' {
-			l.line += 1
+			l.newLine()
 		}
 		l.readChar()
 	}
 }
 
+// newLine records that the current character is a line break.
+func (l *Lexer) newLine() {
+	l.line += 1
+	l.lineStart = l.position + 1
+}
+
 func (l *Lexer) peekChar() byte {
 	if l.readPosition >= len(l.input) {
 		return 0
@@ -105,6 +121,7 @@ func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
+	column := l.position - l.lineStart + 1
 
 	switch l.ch {
 	case ';':
@@ -147,6 +164,18 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = token.New(token.BANG, string(l.ch), l.line)
 		}
+	case '#':
+		if l.peekChar() == '{' {
+			ch := l.ch
+			l.readChar()
+			tok = token.New(token.SET_BRACE, string(ch)+string(l.ch), l.line)
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch, l.line)
+		}
+	case '|':
+		tok = token.New(token.PIPE, string(l.ch), l.line)
+	case '&':
+		tok = token.New(token.AMPERSAND, string(l.ch), l.line)
 	case '/':
 		tok = token.New(token.SLASH, string(l.ch), l.line)
 	case '*':
@@ -182,15 +211,19 @@ func (l *Lexer) NextToken() token.Token {
 		if isAlpha(l.ch) {
 			lit := l.readIdentifier()
 			tok = token.New(token.LookupIdent(lit), lit, l.line)
+			tok.Column = column
 			return tok
 		} else if isDigit(l.ch) {
 			tok := token.New(token.NUM, l.readNumber(), l.line)
+			tok.Column = column
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch, l.line)
 		}
 	}
 
+	tok.Column = column
+
 	l.readChar()
 	return tok
 }
@@ -31,6 +31,9 @@ if (5 < 10) {
 "foo bar";
 [1, 2];
 {"foo": "bar"};
+try { throw e; } catch (e) {} finally {}
+for (x in #{1} | a & b) {}
+#
 `
 
 	tests := []struct {
@@ -144,8 +147,38 @@ if (5 < 10) {
 		{token.STRING, "bar", 24},
 		{token.RBRACE, "}", 24},
 		{token.SEMICOLON, ";", 24},
+		{token.TRY, "try", 25},
+		{token.LBRACE, "{", 25},
+		{token.THROW, "throw", 25},
+		{token.IDENT, "e", 25},
+		{token.SEMICOLON, ";", 25},
+		{token.RBRACE, "}", 25},
+		{token.CATCH, "catch", 25},
+		{token.LPAREN, "(", 25},
+		{token.IDENT, "e", 25},
+		{token.RPAREN, ")", 25},
+		{token.LBRACE, "{", 25},
+		{token.RBRACE, "}", 25},
+		{token.FINALLY, "finally", 25},
+		{token.LBRACE, "{", 25},
+		{token.RBRACE, "}", 25},
+		{token.FOR, "for", 26},
+		{token.LPAREN, "(", 26},
+		{token.IDENT, "x", 26},
+		{token.IN, "in", 26},
+		{token.SET_BRACE, "#{", 26},
+		{token.NUM, "1", 26},
+		{token.RBRACE, "}", 26},
+		{token.PIPE, "|", 26},
+		{token.IDENT, "a", 26},
+		{token.AMPERSAND, "&", 26},
+		{token.IDENT, "b", 26},
+		{token.RPAREN, ")", 26},
+		{token.LBRACE, "{", 26},
+		{token.RBRACE, "}", 26},
+		{token.ILLEGAL, "#", 27},
 
-		{token.EOF, "", 25},
+		{token.EOF, "", 28},
 	}
 
 	l := New(input)
@@ -166,3 +199,64 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  if (x >= 10) {
+	"multi
+line" + y
+}`
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.EQ, 1, 7},
+		{token.NUM, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IF, 2, 3},
+		{token.LPAREN, 2, 6},
+		{token.IDENT, 2, 7},
+		{token.GT_EQ, 2, 9},
+		{token.NUM, 2, 12},
+		{token.RPAREN, 2, 14},
+		{token.LBRACE, 2, 16},
+		{token.STRING, 3, 2},
+		{token.PLUS, 4, 7},
+		{token.IDENT, 4, 9},
+		{token.RBRACE, 5, 1},
+		{token.EOF, 5, 2},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Line != tt.expectedLine {
+			t.Fatalf("tests[%d] - line wrong. expected=%d, got=%d", i, tt.expectedLine, tok.Line)
+		}
+
+		if tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - column wrong. expected=%d, got=%d", i, tt.expectedColumn, tok.Column)
+		}
+	}
+}
+
+func TestNewAt(t *testing.T) {
+	l := NewAt("x\n  y", 7)
+
+	for _, expected := range []token.Token{{Type: token.IDENT, Line: 7, Column: 1}, {Type: token.IDENT, Line: 8, Column: 3}} {
+		tok := l.NextToken()
+		if tok.Line != expected.Line || tok.Column != expected.Column {
+			t.Errorf("wrong position of %q. expected=%d:%d, got=%d:%d", tok.Literal, expected.Line, expected.Column, tok.Line, tok.Column)
+		}
+	}
+}
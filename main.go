@@ -1,13 +1,51 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"github.com/butlermatt/monlox/evaluator"
 	"github.com/butlermatt/monlox/repl"
 	"os"
 	"os/user"
 )
 
+var files = flag.String("files", "", "directory the file builtins may read and write; file access is disabled if empty")
+
 func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-files dir] [script]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Scripts may only reach files in a directory the user has given them.
+	var fsys evaluator.FS
+	var dir *evaluator.DirFS
+	if *files != "" {
+		var err error
+		if dir, err = evaluator.OpenDirFS(*files); err != nil {
+			fmt.Fprintf(os.Stderr, "could not open %s: %v\n", *files, err)
+			os.Exit(1)
+		}
+		fsys = dir
+	}
+
+	ok := run(fsys)
+	if dir != nil {
+		dir.Close()
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// run runs the script named by the first argument, or else the REPL, with fsys as the filesystem of the
+// file builtins. Returns false if the script failed.
+func run(fsys evaluator.FS) bool {
+	if flag.NArg() > 0 {
+		return runFile(flag.Arg(0), fsys)
+	}
+
 	usr, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -15,6 +53,19 @@ func main() {
 
 	fmt.Printf("Hello %s! This is the Monlox programming language!\n", usr.Username)
 	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	repl.Start(os.Stdin, os.Stdout, fsys)
 	fmt.Printf("Good Byte!\n")
+
+	return true
+}
+
+// runFile evaluates the script at path, returning false if it fails.
+func runFile(path string, fsys evaluator.FS) bool {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not read %s: %v\n", path, err)
+		return false
+	}
+
+	return repl.Run(path, string(src), os.Stderr, fsys)
 }
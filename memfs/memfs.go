@@ -0,0 +1,62 @@
+// Package memfs provides a filesystem held in memory, so that tests of the file builtins and of programs
+// embedding the evaluator need not touch the disk.
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"testing/fstest"
+)
+
+// FS is an evaluator.FS held in memory, for tests. Directories are implied by the names of the files in
+// them.
+type FS struct {
+	fstest.MapFS
+}
+
+// New returns an empty FS.
+func New() *FS {
+	return &FS{MapFS: fstest.MapFS{}}
+}
+
+func (m *FS) WriteFile(name string, data []byte) error {
+	if err := m.checkWritable("write", name); err != nil {
+		return err
+	}
+
+	m.MapFS[name] = &fstest.MapFile{Data: append([]byte(nil), data...), Mode: 0644}
+
+	return nil
+}
+
+func (m *FS) AppendFile(name string, data []byte) error {
+	if err := m.checkWritable("append", name); err != nil {
+		return err
+	}
+
+	file, ok := m.MapFS[name]
+	if !ok {
+		file = &fstest.MapFile{Mode: 0644}
+		m.MapFS[name] = file
+	}
+	file.Data = append(file.Data, data...)
+
+	return nil
+}
+
+// checkWritable returns an error if name may not be written by op.
+func (m *FS) checkWritable(op, name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	if info, err := fs.Stat(m.MapFS, name); err == nil && info.IsDir() {
+		return &fs.PathError{Op: op, Path: name, Err: errors.New("is a directory")}
+	}
+
+	if m.MapFS == nil {
+		m.MapFS = fstest.MapFS{}
+	}
+
+	return nil
+}
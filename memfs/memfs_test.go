@@ -0,0 +1,36 @@
+package memfs
+
+import (
+	"io/fs"
+	"testing"
+)
+
+func TestFS(t *testing.T) {
+	fsys := New()
+
+	if err := fsys.WriteFile("a/b.txt", []byte("b")); err != nil {
+		t.Fatalf("WriteFile returned an error: %v", err)
+	}
+
+	data := []byte("c")
+	if err := fsys.AppendFile("a/c.txt", data); err != nil {
+		t.Fatalf("AppendFile returned an error: %v", err)
+	}
+	data[0] = 'x'
+
+	if got, err := fs.ReadFile(fsys, "a/c.txt"); err != nil || string(got) != "c" {
+		t.Errorf("wrong contents of a/c.txt. got=%q, err=%v", got, err)
+	}
+
+	entries, err := fs.ReadDir(fsys, "a")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("wrong entries of a. got=%v, err=%v", entries, err)
+	}
+
+	if err := fsys.WriteFile("a", nil); err == nil {
+		t.Errorf("writing over a directory succeeded")
+	}
+	if err := (&FS{}).WriteFile("x.txt", nil); err != nil {
+		t.Errorf("writing to a zero FS returned an error: %v", err)
+	}
+}
@@ -31,3 +31,20 @@ func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
 }
+
+// Names returns the names of all variables visible from the Environment, including those of outer scopes.
+func (e *Environment) Names() []string {
+	seen := make(map[string]bool)
+
+	var names []string
+	for env := e; env != nil; env = env.outer {
+		for name := range env.store {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
@@ -2,14 +2,26 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"github.com/butlermatt/monlox/ast"
 	"hash/fnv"
+	"io"
 	"math"
+	"regexp"
 	"strings"
+	"time"
 )
 
-type BuiltinFunction func(line int, args ...Object) Object
+// Caller calls functions on behalf of builtins, such as the callback passed to map.
+type Caller interface {
+	// Call calls fn, which may be a Function or a Builtin, with args as if it was called on line.
+	Call(line int, fn Object, args ...Object) Object
+}
+
+// BuiltinFunction implements a builtin. It is passed the Caller evaluating it so that it can call functions
+// given to it as arguments.
+type BuiltinFunction func(c Caller, line int, args ...Object) Object
 
 type HashKey struct {
 	Type  Type
@@ -33,6 +45,11 @@ const (
 	BUILTIN
 	HASH
 	ERROR
+	TAIL_CALL
+	SET
+	REGEX
+	TIME
+	DURATION
 )
 
 func (t Type) String() string {
@@ -57,6 +74,16 @@ func (t Type) String() string {
 		return "HASH"
 	case ERROR:
 		return "ERROR"
+	case TAIL_CALL:
+		return "TAIL_CALL"
+	case SET:
+		return "SET"
+	case REGEX:
+		return "REGEX"
+	case TIME:
+		return "TIME"
+	case DURATION:
+		return "DURATION"
 	}
 
 	return ""
@@ -74,7 +101,13 @@ type Number struct {
 func (n *Number) Type() Type      { return NUMBER }
 func (n *Number) Inspect() string { return fmt.Sprintf("%v", n.Value) }
 func (n *Number) HashKey() HashKey {
-	return HashKey{Type: n.Type(), Value: math.Float64bits(float64(n.Value))}
+	value := n.Value
+	if value == 0 {
+		// -0 is equal to 0, so must hash the same.
+		value = 0
+	}
+
+	return HashKey{Type: n.Type(), Value: math.Float64bits(float64(value))}
 }
 
 type Boolean struct {
@@ -107,15 +140,121 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() Type      { return RETURN }
 func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
 
+// TailCall is a call in tail position of a function body which has not been applied yet. The evaluator
+// returns it in place of the call result so the caller can apply it without growing the stack.
+type TailCall struct {
+	Function  Object
+	Arguments []Object
+	Line      int
+	Column    int
+}
+
+func (tc *TailCall) Type() Type      { return TAIL_CALL }
+func (tc *TailCall) Inspect() string { return "tail call" }
+
+// Frame is a function call on the call stack.
+type Frame struct {
+	Function string // Name of the called function. Empty for anonymous functions.
+	Line     int    // Line the function was called from.
+}
+
+// ErrorKind classifies an Error, allowing errors to be matched without inspecting their message.
+type ErrorKind string
+
+const (
+	UserError      ErrorKind = "Error"          // A value thrown by a throw statement.
+	TypeError      ErrorKind = "TypeError"      // An operation applied to values of the wrong type.
+	NameError      ErrorKind = "NameError"      // An identifier which is not defined.
+	IndexError     ErrorKind = "IndexError"     // An index outside the bounds of a collection.
+	ValueError     ErrorKind = "ValueError"     // A value of the right type that an operation cannot accept.
+	IOError        ErrorKind = "IOError"        // A file operation which failed or which the host does not allow.
+	ArityError     ErrorKind = "ArityError"     // A function called with the wrong number of arguments.
+	RecursionError ErrorKind = "RecursionError" // The maximum call depth was exceeded.
+	CancelledError ErrorKind = "CancelledError" // Evaluation was cancelled by the host.
+	TimeoutError   ErrorKind = "TimeoutError"   // Evaluation ran past the deadline set by the host.
+	StepLimitError ErrorKind = "StepLimitError" // Evaluation exhausted its step budget.
+	InternalError  ErrorKind = "InternalError"  // A bug in the interpreter or a builtin, recovered from a panic.
+)
+
 type Error struct {
+	Kind    ErrorKind
 	Message string
 	Line    int
+	// Column is the byte offset within Line the error occurred at, starting at 1. It is 0 if unknown.
+	Column int
+	// Operator is the operator which failed to apply, if any.
+	Operator string
+	// Operands are the values the error relates to, such as the operands of Operator or the argument
+	// passed to a builtin.
+	Operands []Object
+	// Err is the Go error which caused evaluation to be aborted, such as a cancelled context, if any.
+	Err error
+	// Stack is the call stack at the point the error occurred, outermost call first.
+	Stack []Frame
+	// Value is the value thrown by a throw statement. It is nil for runtime errors.
+	Value Object
 }
 
 func (e *Error) Type() Type      { return ERROR }
-func (e *Error) Inspect() string { return fmt.Sprintf("ERROR line %d: %s", e.Line, e.Message) }
+func (e *Error) Inspect() string { return fmt.Sprintf("%s line %d: %s", e.Kind, e.Line, e.Message) }
+
+// Traceback returns a Python style traceback of the calls which led to the error, followed by the error.
+// Runs of identical frames, such as from runaway recursion, are collapsed.
+func (e *Error) Traceback() string {
+	return e.StackTrace() + e.Inspect()
+}
+
+// StackTrace returns the frames of the Traceback, without the error itself. It is empty if the error did
+// not occur within a function.
+func (e *Error) StackTrace() string {
+	if len(e.Stack) == 0 {
+		return ""
+	}
+
+	var lines []string
+	caller := "<program>"
+	for _, f := range e.Stack {
+		lines = append(lines, fmt.Sprintf("  line %d, in %s", f.Line, caller))
+
+		caller = f.Function
+		if caller == "" {
+			caller = "<anonymous>"
+		}
+	}
+	lines = append(lines, fmt.Sprintf("  line %d, in %s", e.Line, caller))
+
+	var out bytes.Buffer
+
+	out.WriteString("Traceback (most recent call last):\n")
+	for i := 0; i < len(lines); {
+		run := 1
+		for i+run < len(lines) && lines[i+run] == lines[i] {
+			run++
+		}
+
+		shown := run
+		if shown > maxRepeatedFrames {
+			shown = maxRepeatedFrames
+		}
+		for j := 0; j < shown; j++ {
+			out.WriteString(lines[i] + "\n")
+		}
+		if run > shown {
+			fmt.Fprintf(&out, "  [Previous line repeated %d more times]\n", run-shown)
+		}
+
+		i += run
+	}
+
+	return out.String()
+}
+
+// maxRepeatedFrames is the number of identical consecutive frames shown in a traceback before the rest are
+// collapsed into a single line.
+const maxRepeatedFrames = 3
 
 type Function struct {
+	Name       string
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
@@ -152,6 +291,30 @@ func (s *String) HashKey() HashKey {
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
 
+// Regex is a compiled regular expression, in the syntax of Go's regexp package.
+type Regex struct {
+	Regexp *regexp.Regexp
+}
+
+func (r *Regex) Type() Type      { return REGEX }
+func (r *Regex) Inspect() string { return "/" + r.Regexp.String() + "/" }
+
+// Time is an instant in time.
+type Time struct {
+	Value time.Time
+}
+
+func (t *Time) Type() Type      { return TIME }
+func (t *Time) Inspect() string { return t.Value.Format(time.RFC3339Nano) }
+
+// Duration is the time between two instants.
+type Duration struct {
+	Value time.Duration
+}
+
+func (d *Duration) Type() Type      { return DURATION }
+func (d *Duration) Inspect() string { return d.Value.String() }
+
 type Builtin struct {
 	Fn BuiltinFunction
 }
@@ -179,13 +342,31 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
+// HashKey combines the HashKeys of the elements of a in order.
+func (a *Array) HashKey() HashKey {
+	hasher := fnv.New64a()
+	for _, e := range a.Elements {
+		writeHashKey(hasher, hashKeyOf(e))
+	}
+
+	return HashKey{Type: a.Type(), Value: hasher.Sum64()}
+}
+
 type HashPair struct {
 	Key   Object
 	Value Object
 }
 
+// Hash maps keys to values, keeping them in the order they were first set. Keys are found by their
+// HashKey, and compared with Equal to tell apart keys whose HashKeys collide.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	pairs   []HashPair
+	buckets map[HashKey][]int // Indexes into pairs of the keys with each HashKey.
+}
+
+// NewHash returns an empty Hash.
+func NewHash() *Hash {
+	return &Hash{buckets: make(map[HashKey][]int)}
 }
 
 func (h *Hash) Type() Type { return HASH }
@@ -193,7 +374,7 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	var pairs []string
-	for _, pair := range h.Pairs {
+	for _, pair := range h.Pairs() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 
@@ -203,3 +384,254 @@ func (h *Hash) Inspect() string {
 
 	return out.String()
 }
+
+// HashKey combines the HashKeys of the pairs of h so that it does not depend on the order they are stored in.
+func (h *Hash) HashKey() HashKey {
+	var sum uint64
+	for _, pair := range h.Pairs() {
+		hasher := fnv.New64a()
+		writeHashKey(hasher, hashKeyOf(pair.Key))
+		writeHashKey(hasher, hashKeyOf(pair.Value))
+		sum += hasher.Sum64()
+	}
+
+	return HashKey{Type: h.Type(), Value: sum}
+}
+
+// Get returns the value stored for key, and whether there is one.
+func (h *Hash) Get(key Object) (Object, bool) {
+	for _, i := range h.buckets[hashKeyOf(key)] {
+		if Equal(h.pairs[i].Key, key) {
+			return h.pairs[i].Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// Set stores value for key. If an equal key is already stored its value is replaced, and it keeps its place
+// in the order of h.
+func (h *Hash) Set(key, value Object) {
+	if h.buckets == nil {
+		h.buckets = make(map[HashKey][]int)
+	}
+
+	hashed := hashKeyOf(key)
+	for _, i := range h.buckets[hashed] {
+		if Equal(h.pairs[i].Key, key) {
+			h.pairs[i].Value = value
+			return
+		}
+	}
+
+	h.buckets[hashed] = append(h.buckets[hashed], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+}
+
+// Delete removes key and its value from h, and reports whether it was there.
+func (h *Hash) Delete(key Object) bool {
+	hashed := hashKeyOf(key)
+	for _, i := range h.buckets[hashed] {
+		if !Equal(h.pairs[i].Key, key) {
+			continue
+		}
+
+		h.pairs = append(h.pairs[:i], h.pairs[i+1:]...)
+
+		// The pairs after the removed one have moved down, so their indexes must be rebuilt.
+		h.buckets = make(map[HashKey][]int, len(h.pairs))
+		for j, pair := range h.pairs {
+			k := hashKeyOf(pair.Key)
+			h.buckets[k] = append(h.buckets[k], j)
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// Len returns the number of pairs in h.
+func (h *Hash) Len() int { return len(h.pairs) }
+
+// Pairs returns the pairs stored in h, in the order their keys were first set.
+func (h *Hash) Pairs() []HashPair {
+	pairs := make([]HashPair, len(h.pairs))
+	copy(pairs, h.pairs)
+
+	return pairs
+}
+
+// Set is a collection of distinct values, kept in the order they were first added. Values are compared
+// in the same way as the keys of a Hash.
+type Set struct {
+	elements Hash // Maps each element to itself.
+}
+
+// NewSet returns a Set holding elements.
+func NewSet(elements ...Object) *Set {
+	s := &Set{}
+	for _, e := range elements {
+		s.Add(e)
+	}
+
+	return s
+}
+
+func (s *Set) Type() Type { return SET }
+func (s *Set) Inspect() string {
+	var out bytes.Buffer
+
+	var elements []string
+	for _, e := range s.Elements() {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("#{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteByte('}')
+
+	return out.String()
+}
+
+// HashKey combines the HashKeys of the elements of s so that it does not depend on the order they were added in.
+func (s *Set) HashKey() HashKey {
+	var sum uint64
+	for _, e := range s.Elements() {
+		hasher := fnv.New64a()
+		writeHashKey(hasher, hashKeyOf(e))
+		sum += hasher.Sum64()
+	}
+
+	return HashKey{Type: s.Type(), Value: sum}
+}
+
+// Add adds obj to s, unless an equal value is already in it.
+func (s *Set) Add(obj Object) {
+	if !s.Contains(obj) {
+		s.elements.Set(obj, obj)
+	}
+}
+
+// Remove removes obj from s, and reports whether it was there.
+func (s *Set) Remove(obj Object) bool { return s.elements.Delete(obj) }
+
+// Contains reports whether a value equal to obj is in s.
+func (s *Set) Contains(obj Object) bool {
+	_, ok := s.elements.Get(obj)
+	return ok
+}
+
+// Len returns the number of elements in s.
+func (s *Set) Len() int { return s.elements.Len() }
+
+// Elements returns the elements of s, in the order they were first added.
+func (s *Set) Elements() []Object {
+	var elements []Object
+	for _, pair := range s.elements.pairs {
+		elements = append(elements, pair.Key)
+	}
+
+	return elements
+}
+
+// hashKeyOf returns the HashKey of obj. Objects that are not Hashable are only told apart by their type,
+// which leaves Equal to compare them by identity.
+func hashKeyOf(obj Object) HashKey {
+	if h, ok := obj.(Hashable); ok {
+		return h.HashKey()
+	}
+
+	return HashKey{Type: obj.Type()}
+}
+
+func writeHashKey(w io.Writer, key HashKey) {
+	var buf [16]byte
+	binary.LittleEndian.PutUint64(buf[:8], uint64(key.Type))
+	binary.LittleEndian.PutUint64(buf[8:], key.Value)
+	w.Write(buf[:])
+}
+
+// Equal reports whether a and b hold the same value. Arrays and hashes are compared element by element, so
+// they are equal when their contents are, even if they are different objects. Other objects that do not
+// hold a value, such as functions, are only equal to themselves.
+func Equal(a, b Object) bool {
+	return equal(a, b, make(map[[2]Object]bool))
+}
+
+// equal compares a and b, recording the pairs of containers being compared in seen. A pair met again
+// while it is still being compared must belong to a cycle, and is assumed equal so the comparison ends.
+func equal(a, b Object, seen map[[2]Object]bool) bool {
+	if a == b {
+		return true
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Number:
+		return a.Value == b.(*Number).Value
+	case *Boolean:
+		return a.Value == b.(*Boolean).Value
+	case *String:
+		return a.Value == b.(*String).Value
+	case *Regex:
+		return a.Regexp.String() == b.(*Regex).Regexp.String()
+	case *Time:
+		return a.Value.Equal(b.(*Time).Value)
+	case *Duration:
+		return a.Value == b.(*Duration).Value
+	case *Array:
+		b := b.(*Array)
+		if len(a.Elements) != len(b.Elements) {
+			return false
+		}
+
+		pair := [2]Object{a, b}
+		if seen[pair] {
+			return true
+		}
+		seen[pair] = true
+
+		for i := range a.Elements {
+			if !equal(a.Elements[i], b.Elements[i], seen) {
+				return false
+			}
+		}
+		return true
+	case *Set:
+		b := b.(*Set)
+		if a.Len() != b.Len() {
+			return false
+		}
+
+		for _, e := range a.Elements() {
+			if !b.Contains(e) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		b := b.(*Hash)
+		if a.Len() != b.Len() {
+			return false
+		}
+
+		pair := [2]Object{a, b}
+		if seen[pair] {
+			return true
+		}
+		seen[pair] = true
+
+		for _, pa := range a.Pairs() {
+			vb, ok := b.Get(pa.Key)
+			if !ok || !equal(pa.Value, vb, seen) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
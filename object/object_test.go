@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+	"sort"
+	"strings"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -58,3 +62,183 @@ func TestBooleanHashKey(t *testing.T) {
 		t.Errorf("strings with different content have the same hash keys")
 	}
 }
+
+func TestErrorTraceback(t *testing.T) {
+	tests := []struct {
+		err      *Error
+		expected string
+	}{
+		{&Error{Kind: TypeError, Message: "oops", Line: 1}, "TypeError line 1: oops"},
+		{
+			&Error{Kind: TypeError, Message: "oops", Line: 4, Stack: []Frame{{Function: "f", Line: 7}, {Line: 2}}},
+			"Traceback (most recent call last):\n  line 7, in <program>\n  line 2, in f\n  line 4, in <anonymous>\nTypeError line 4: oops",
+		},
+		{
+			&Error{Kind: RecursionError, Message: "deep", Line: 2, Stack: []Frame{{Function: "f", Line: 5}, {Function: "f", Line: 2}, {Function: "f", Line: 2}, {Function: "f", Line: 2}, {Function: "f", Line: 2}, {Function: "f", Line: 2}}},
+			"Traceback (most recent call last):\n  line 5, in <program>\n  line 2, in f\n  line 2, in f\n  line 2, in f\n  [Previous line repeated 3 more times]\nRecursionError line 2: deep",
+		},
+	}
+
+	for i, tt := range tests {
+		if tb := tt.err.Traceback(); tb != tt.expected {
+			t.Errorf("test %d: traceback wrong.\nexpected=%s\ngot=%s", i, tt.expected, tb)
+		}
+	}
+}
+
+func TestEnvironmentNames(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("a", &Number{Value: 1})
+	outer.Set("b", &Number{Value: 2})
+
+	inner := NewEnclodedEnvironment(outer)
+	inner.Set("b", &Number{Value: 3})
+	inner.Set("c", &Number{Value: 4})
+
+	names := inner.Names()
+	sort.Strings(names)
+
+	if strings.Join(names, ",") != "a,b,c" {
+		t.Errorf("wrong names. expected=%v, got=%v", []string{"a", "b", "c"}, names)
+	}
+}
+
+func TestEqualCycles(t *testing.T) {
+	a := &Array{Elements: []Object{&Number{Value: 1}}}
+	a.Elements = append(a.Elements, a)
+	b := &Array{Elements: []Object{&Number{Value: 1}}}
+	b.Elements = append(b.Elements, b)
+
+	if !Equal(a, b) {
+		t.Errorf("arrays with equal cycles are not equal")
+	}
+
+	c := &Array{Elements: []Object{&Number{Value: 2}}}
+	c.Elements = append(c.Elements, c)
+
+	if Equal(a, c) {
+		t.Errorf("arrays with different cycles are equal")
+	}
+
+	h1 := NewHash()
+	h1.Set(&String{Value: "self"}, h1)
+	h2 := NewHash()
+	h2.Set(&String{Value: "self"}, h2)
+
+	if !Equal(h1, h2) {
+		t.Errorf("hashes with equal cycles are not equal")
+	}
+}
+
+func TestArrayHashKey(t *testing.T) {
+	a1 := &Array{Elements: []Object{&Number{Value: 1}, &String{Value: "a"}}}
+	a2 := &Array{Elements: []Object{&Number{Value: 1}, &String{Value: "a"}}}
+	diff := &Array{Elements: []Object{&String{Value: "a"}, &Number{Value: 1}}}
+
+	if a1.HashKey() != a2.HashKey() {
+		t.Errorf("arrays with same content have different hash keys")
+	}
+
+	if a1.HashKey() == diff.HashKey() {
+		t.Errorf("arrays with different content have same hash keys")
+	}
+}
+
+func TestHashHashKey(t *testing.T) {
+	h1 := NewHash()
+	h1.Set(&String{Value: "a"}, &Number{Value: 1})
+	h1.Set(&String{Value: "b"}, &Number{Value: 2})
+	h2 := NewHash()
+	h2.Set(&String{Value: "b"}, &Number{Value: 2})
+	h2.Set(&String{Value: "a"}, &Number{Value: 1})
+	diff := NewHash()
+	diff.Set(&String{Value: "a"}, &Number{Value: 2})
+	diff.Set(&String{Value: "b"}, &Number{Value: 1})
+
+	if h1.HashKey() != h2.HashKey() {
+		t.Errorf("hashes with same content have different hash keys")
+	}
+
+	if h1.HashKey() == diff.HashKey() {
+		t.Errorf("hashes with different content have same hash keys")
+	}
+}
+
+func TestHashCollisions(t *testing.T) {
+	// Objects that are not Hashable all share the HashKey of their type.
+	h := NewHash()
+	a := &Builtin{}
+	b := &Builtin{}
+
+	h.Set(a, &Number{Value: 1})
+	h.Set(b, &Number{Value: 2})
+	h.Set(a, &Number{Value: 3})
+
+	if h.Len() != 2 {
+		t.Fatalf("hash has wrong length. expected=2, got=%d", h.Len())
+	}
+
+	for _, tt := range []struct {
+		key      Object
+		expected float32
+	}{{a, 3}, {b, 2}} {
+		value, ok := h.Get(tt.key)
+		if !ok {
+			t.Errorf("no value for key %p", tt.key)
+			continue
+		}
+		if value.(*Number).Value != tt.expected {
+			t.Errorf("wrong value for key %p. expected=%v, got=%v", tt.key, tt.expected, value.Inspect())
+		}
+	}
+
+	if _, ok := h.Get(&Builtin{}); ok {
+		t.Errorf("found value for key not in hash")
+	}
+}
+
+func TestHashDelete(t *testing.T) {
+	h := NewHash()
+	for i, key := range []string{"a", "b", "c"} {
+		h.Set(&String{Value: key}, &Number{Value: float32(i)})
+	}
+
+	if !h.Delete(&String{Value: "b"}) {
+		t.Fatalf("key not deleted")
+	}
+	if h.Delete(&String{Value: "b"}) {
+		t.Errorf("deleted key found again")
+	}
+
+	if h.Inspect() != "{a: 0, c: 2}" {
+		t.Errorf("wrong hash after delete. got=%q", h.Inspect())
+	}
+
+	if value, ok := h.Get(&String{Value: "c"}); !ok || value.(*Number).Value != 2 {
+		t.Errorf("key after deleted key not found. got=%v", value)
+	}
+}
+
+func TestSet(t *testing.T) {
+	s := NewSet(&Number{Value: 1}, &Number{Value: 2}, &Number{Value: 1})
+
+	if s.Len() != 2 {
+		t.Errorf("set has wrong length. expected=2, got=%d", s.Len())
+	}
+
+	if !s.Contains(&Number{Value: 2}) || s.Contains(&Number{Value: 3}) {
+		t.Errorf("wrong membership for %s", s.Inspect())
+	}
+
+	s.Add(&Number{Value: 3})
+	s.Remove(&Number{Value: 1})
+
+	if s.Inspect() != "#{2, 3}" {
+		t.Errorf("wrong set. expected=%q, got=%q", "#{2, 3}", s.Inspect())
+	}
+
+	other := NewSet(&Number{Value: 3}, &Number{Value: 2})
+	if !Equal(s, other) || s.HashKey() != other.HashKey() {
+		t.Errorf("sets with same elements in different order not equal")
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/butlermatt/monlox/ast"
 	"github.com/butlermatt/monlox/lexer"
+	"github.com/butlermatt/monlox/suggest"
 	"github.com/butlermatt/monlox/token"
 )
 
@@ -14,7 +15,9 @@ const (
 	lowest
 	logical // and/or
 	equals  // ==
-	ltgt    // < or >
+	ltgt    // < or > or in
+	union   // |
+	inter   // &
 	sum     // + or -
 	product // * or /
 	prefix  // -X or !X
@@ -28,29 +31,47 @@ type (
 )
 
 var precedences = map[token.TokenType]int{
-	token.AND:      logical,
-	token.OR:       logical,
-	token.EQ_EQ:    equals,
-	token.NOT_EQ:   equals,
-	token.LT:       ltgt,
-	token.LT_EQ:    ltgt,
-	token.GT:       ltgt,
-	token.GT_EQ:    ltgt,
-	token.PLUS:     sum,
-	token.MINUS:    sum,
-	token.ASTERISK: product,
-	token.SLASH:    product,
-	token.LPAREN:   call,
-	token.LBRACKET: index,
+	token.AND:       logical,
+	token.OR:        logical,
+	token.EQ_EQ:     equals,
+	token.NOT_EQ:    equals,
+	token.LT:        ltgt,
+	token.LT_EQ:     ltgt,
+	token.GT:        ltgt,
+	token.GT_EQ:     ltgt,
+	token.IN:        ltgt,
+	token.PIPE:      union,
+	token.AMPERSAND: inter,
+	token.PLUS:      sum,
+	token.MINUS:     sum,
+	token.ASTERISK:  product,
+	token.SLASH:     product,
+	token.LPAREN:    call,
+	token.LBRACKET:  index,
+}
+
+// ParseError is a syntax error found while parsing.
+type ParseError struct {
+	Line     int
+	Column   int
+	Expected []token.TokenType // The tokens which would have been valid instead of Found, if known.
+	Found    token.Token       // The token at which the error was found.
+	Message  string
+}
+
+// Error returns the message of the error prefixed with the line it was found on.
+func (pe *ParseError) Error() string {
+	return fmt.Sprintf("on line %d: %s", pe.Line, pe.Message)
 }
 
 // Parser tries to parse the provided tokens with the language rules, and catches errors.
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	errors []*ParseError
 
 	curToken  token.Token
 	peekToken token.Token
+	blockEnd  token.Token // The closing brace of the last block parsed.
 
 	prefixFns map[token.TokenType]prefixParseFn
 	infixFns  map[token.TokenType]infixParseFn
@@ -58,7 +79,7 @@ type Parser struct {
 
 // New returns a new Parser populated with tokens from the specified Lexer.
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []*ParseError{}}
 
 	p.prefixFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
@@ -74,6 +95,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.UTSTRING, p.parseUnterminatedString)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.SET_BRACE, p.parseSetLiteral)
 
 	p.infixFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -88,6 +110,9 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.IN, p.parseInfixExpression)
+	p.registerInfix(token.PIPE, p.parseInfixExpression)
+	p.registerInfix(token.AMPERSAND, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 
@@ -112,17 +137,46 @@ func (p *Parser) nextToken() {
 }
 
 // Errors returns a slice of errors generated when parsing the tokens.
-func (p *Parser) Errors() []string {
+func (p *Parser) Errors() []*ParseError {
 	return p.errors
 }
 
-// ParseProgram steps through the tokens to compile the statements.
+// addError records a syntax error at tok. An error at the same token as the last one is dropped, as it is
+// one of the enclosing expressions failing on the token which broke an inner one.
+func (p *Parser) addError(tok token.Token, expected []token.TokenType, format string, a ...interface{}) {
+	if len(p.errors) > 0 && p.errors[len(p.errors)-1].Found == tok {
+		return
+	}
+
+	p.errors = append(p.errors, &ParseError{
+		Line:     tok.Line,
+		Column:   tok.Column,
+		Expected: expected,
+		Found:    tok,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+// ParseProgram steps through the tokens to compile the statements. Parsing continues after a syntax error
+// so that every independent error in the program is reported.
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 
 	for p.curToken.Type != token.EOF {
+		errs := len(p.errors)
 		stmt := p.parseStatement()
+		if len(p.errors) > errs {
+			// A closing brace at the top level has no block to end, so synchronize stops on it without
+			// moving on. Skip it once it has been reported.
+			start := p.curToken
+			p.synchronize()
+			if p.curToken == start {
+				p.nextToken()
+			}
+			continue
+		}
+
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
@@ -133,6 +187,38 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+// synchronize discards tokens after a syntax error up to the start of the next statement, so that errors
+// caused by the tokens of the broken statement are not reported. It stops after a semicolon, at a keyword
+// which begins a statement, or at a closing brace which may end the enclosing block. Braces opened by the
+// discarded tokens, such as those of a hash literal, are discarded along with what they enclose.
+func (p *Parser) synchronize() {
+	depth := 0
+	for !p.curTokenIs(token.EOF) {
+		switch {
+		case p.curTokenIs(token.LBRACE) || p.curTokenIs(token.SET_BRACE):
+			depth++
+		case p.curTokenIs(token.RBRACE) && depth > 0:
+			depth--
+		case p.curTokenIs(token.RBRACE) && p.curToken != p.blockEnd:
+			return
+		case p.curTokenIs(token.SEMICOLON) && depth == 0:
+			p.nextToken()
+			return
+		}
+
+		p.nextToken()
+
+		if depth > 0 {
+			continue
+		}
+
+		switch p.curToken.Type {
+		case token.LET, token.RETURN, token.THROW, token.TRY, token.FOR:
+			return
+		}
+	}
+}
+
 func (p *Parser) curTokenIs(t token.TokenType) bool {
 	return p.curToken.Type == t
 }
@@ -151,8 +237,7 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("on line %d: expected next token to be %s, got %s instead", p.peekToken.Line, t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(p.peekToken, []token.TokenType{t}, "expected next token to be %s, got %s instead", t, p.peekToken.Type)
 }
 
 func (p *Parser) parseStatement() ast.Statement {
@@ -161,6 +246,12 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.TRY:
+		return p.parseTryStatement()
+	case token.FOR:
+		return p.parseForStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -182,6 +273,10 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	p.nextToken()
 	stmt.Value = p.parseExpression(lowest)
 
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		fl.Name = stmt.Name.Value
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -202,11 +297,127 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(lowest)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		stmt.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		stmt.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		stmt.Finally = p.parseBlockStatement()
+	}
+
+	if stmt.Catch == nil && stmt.Finally == nil {
+		expected := []token.TokenType{token.CATCH, token.FINALLY}
+		p.addError(p.peekToken, expected, "expected catch or finally after try block, got %s instead", p.peekToken.Type)
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(lowest)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
 	stmt.Expression = p.parseExpression(lowest)
 
+	if ident, ok := stmt.Expression.(*ast.Identifier); ok && p.peekIsOperand() {
+		// A lone identifier directly followed by another operand on the same line is almost certainly a
+		// misspelled keyword, such as `lett x = 5` or `retrun x`.
+		if kw := suggest.Closest(ident.Value, token.Keywords()); kw != "" {
+			expected := []token.TokenType{token.LookupIdent(kw)}
+			p.addError(ident.Token, expected, "unknown keyword %s, did you mean %s?", ident.Value, kw)
+			return nil
+		}
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -214,10 +425,25 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	return stmt
 }
 
+// peekIsOperand reports whether the peek token, on the same line as the current token, begins an operand
+// rather than continuing the current expression.
+func (p *Parser) peekIsOperand() bool {
+	if p.peekToken.Line != p.curToken.Line {
+		return false
+	}
+
+	switch p.peekToken.Type {
+	case token.IDENT, token.NUM, token.STRING, token.LBRACE:
+		return true
+	}
+
+	return false
+}
+
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixFns[p.curToken.Type]
 	if prefix == nil {
-		p.noPrefixFnError(p.curToken.Line, p.curToken.Type)
+		p.noPrefixFnError(p.curToken)
 		return nil
 	}
 	leftExp := prefix()
@@ -235,14 +461,12 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	return leftExp
 }
 
-func (p *Parser) noPrefixFnError(line int, t token.TokenType) {
-	msg := fmt.Sprintf("on line %d: no prefix parse function for %s found", line, t)
-	p.errors = append(p.errors, msg)
+func (p *Parser) noPrefixFnError(tok token.Token) {
+	p.addError(tok, nil, "no prefix parse function for %s found", tok.Type)
 }
 
 func (p *Parser) parseUnterminatedString() ast.Expression {
-	msg := fmt.Sprintf("on line %d: unterminated string", p.curToken.Line)
-	p.errors = append(p.errors, msg)
+	p.addError(p.curToken, []token.TokenType{token.STRING}, "unterminated string")
 	return nil
 }
 
@@ -255,8 +479,7 @@ func (p *Parser) parseNumberLiteral() ast.Expression {
 
 	value, err := strconv.ParseFloat(p.curToken.Literal, 32)
 	if err != nil {
-		msg := fmt.Sprintf("on line %d: could not parse %q as number", p.curToken.Line, p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.curToken, nil, "could not parse %q as number", p.curToken.Literal)
 	}
 
 	lit.Value = float32(value)
@@ -329,12 +552,19 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		errs := len(p.errors)
 		stmt := p.parseStatement()
+		if len(p.errors) > errs {
+			p.synchronize()
+			continue
+		}
+
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
 	}
+	p.blockEnd = p.curToken
 
 	return block
 }
@@ -460,11 +690,25 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	return array
 }
 
+func (p *Parser) parseSetLiteral() ast.Expression {
+	set := &ast.SetLiteral{Token: p.curToken}
+
+	set.Elements = p.parseExpressionList(token.RBRACE)
+
+	return set
+}
+
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	ex := &ast.IndexExpression{Token: p.curToken, Left: left}
 
-	p.nextToken()
-	ex.Index = p.parseExpression(lowest)
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		ex.Index = p.parseExpression(lowest)
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		return p.parseSliceExpression(ex.Token, left, ex.Index)
+	}
 
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
@@ -473,8 +717,37 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return ex
 }
 
+// parseSliceExpression parses the rest of a slice, from the colon following its start.
+func (p *Parser) parseSliceExpression(tok token.Token, left, start ast.Expression) ast.Expression {
+	slice := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+
+	p.nextToken()
+	slice.End = p.parseSliceBound()
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		slice.Step = p.parseSliceBound()
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return slice
+}
+
+// parseSliceBound parses the bound of a slice following the current colon, returning nil if it is left out.
+func (p *Parser) parseSliceBound() ast.Expression {
+	if p.peekTokenIs(token.COLON) || p.peekTokenIs(token.RBRACKET) {
+		return nil
+	}
+
+	p.nextToken()
+	return p.parseExpression(lowest)
+}
+
 func (p *Parser) parseHashLiteral() ast.Expression {
-	hash := &ast.HashLiteral{Token: p.curToken, Pairs: make(map[ast.Expression]ast.Expression)}
+	hash := &ast.HashLiteral{Token: p.curToken}
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
@@ -487,7 +760,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(lowest)
 
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
 		}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/butlermatt/monlox/ast"
 	"github.com/butlermatt/monlox/lexer"
+	"github.com/butlermatt/monlox/token"
 )
 
 func TestLetStatements(t *testing.T) {
@@ -109,6 +110,155 @@ func TestReturnStatements(t *testing.T) {
 	}
 }
 
+func TestThrowStatements(t *testing.T) {
+	tests := []struct {
+		input string
+		value interface{}
+	}{
+		{"throw 5;", float32(5)},
+		{"throw foobar", "foobar"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program contains incorrect number of Statements. expected=%d, got=%d", 1, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is wrong type. expected=*ast.ThrowStatement, got=%T", program.Statements[0])
+		}
+
+		if !testLiteralExpression(t, stmt.Value, tt.value) {
+			return
+		}
+	}
+}
+
+func TestTryStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { x } catch (e) { y }", "try x catch (e) y"},
+		{"try { x } finally { z }", "try x finally z"},
+		{"try { x } catch (err) { y } finally { z }", "try x catch (err) y finally z"},
+		{"try { x } catch (e) { y };", "try x catch (e) y"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program contains incorrect number of Statements. expected=%d, got=%d", 1, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.TryStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is wrong type. expected=*ast.TryStatement, got=%T", program.Statements[0])
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("stmt.String() wrong. expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestTryStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { x }", "on line 1: expected catch or finally after try block, got EOF instead"},
+		{"try { x } catch { y }", "on line 1: expected next token to be (, got { instead"},
+		{"try { x } catch (1) { y }", "on line 1: expected next token to be IDENT, got NUM instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong parser error. expected=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (x in xs) { puts(x) }", "for (x in xs) puts(x)"},
+		{"for (x in #{1, 2} | ys) { x }", "for (x in (#{1, 2} | ys)) x"},
+		{"for (k in {\"a\": 1}) {}", "for (k in {a:1}) "},
+		{"for (x in xs) { x };", "for (x in xs) x"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program contains incorrect number of Statements. expected=%d, got=%d", 1, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is wrong type. expected=*ast.ForStatement, got=%T", program.Statements[0])
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("stmt.String() wrong. expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestForStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for x in xs { x }", "on line 1: expected next token to be (, got IDENT instead"},
+		{"for (1 in xs) { x }", "on line 1: expected next token to be IDENT, got NUM instead"},
+		{"for (x of xs) { x }", "on line 1: expected next token to be IN, got IDENT instead"},
+		{"for (x in xs) x", "on line 1: expected next token to be {, got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong parser error. expected=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
 func TestIdentifierExpression(t *testing.T) {
 	tests := []struct {
 		input string
@@ -324,6 +474,12 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"-a * b", "((-a) * b)"},
 		{"!-a", "(!(-a))"},
 		{"a + b + c", "((a + b) + c)"},
+		{"a in b == true", "((a in b) == true)"},
+		{"a + 1 in b", "((a + 1) in b)"},
+		{"a | b & c", "(a | (b & c))"},
+		{"a & b | c", "((a & b) | c)"},
+		{"a | b - c", "(a | (b - c))"},
+		{"x in a | b", "(x in (a | b))"},
 		{"a + b - c", "((a + b) - c)"},
 		{"a * b * c", "((a * b) * c)"},
 		{"a * b / c", "((a * b) / c)"},
@@ -390,6 +546,38 @@ func TestParsingArrayLiterals(t *testing.T) {
 	testInfixExpression(t, array.Elements[2], 3, "+", 3)
 }
 
+func TestParsingSetLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"#{}", "#{}"},
+		{"#{1, 2 * 2, \"a\"}", "#{1, (2 * 2), a}"},
+		{"#{[1, 2], #{3}}", "#{[1, 2], #{3}}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program statement is wrong type. expected=*ast.ExpressionStatement, got=%T (%+[1]v)", program.Statements[0])
+		}
+
+		set, ok := stmt.Expression.(*ast.SetLiteral)
+		if !ok {
+			t.Fatalf("expression is wrong type. expected=*ast.SetLiteral, got=%T (%+[1]v)", stmt.Expression)
+		}
+
+		if set.String() != tt.expected {
+			t.Errorf("set.String() wrong. expected=%q, got=%q", tt.expected, set.String())
+		}
+	}
+}
+
 func TestParsingIndexExpressions(t *testing.T) {
 	input := "myArray[1 + 1]"
 
@@ -411,6 +599,39 @@ func TestParsingIndexExpressions(t *testing.T) {
 	testInfixExpression(t, indexExp.Index, 1, "+", 1)
 }
 
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a[1:2]", "(a[1:2])"},
+		{"a[:2]", "(a[:2])"},
+		{"a[1:]", "(a[1:])"},
+		{"a[:]", "(a[:])"},
+		{"a[1:2:3]", "(a[1:2:3])"},
+		{"a[::-1]", "(a[::(-1)])"},
+		{"a[:n - 1:]", "(a[:(n - 1)])"},
+		{"a[b[0]:len(b)]", "(a[(b[0]):len(b)])"},
+		{"a[1:2][0]", "((a[1:2])[0])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.SliceExpression); !ok && tt.input != "a[1:2][0]" {
+			t.Errorf("expression is wrong type. expected=*ast.SliceExpression, got=%T (%+[1]v)", stmt.Expression)
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("wrong slice for %s. expected=%q, got=%q", tt.input, tt.expected, stmt.String())
+		}
+	}
+}
+
 func TestParsingEmptyHashLiteral(t *testing.T) {
 	input := "{}"
 
@@ -461,7 +682,8 @@ func TestParsingHashLiteralStringKeys(t *testing.T) {
 		"three": 3,
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is wrong type. expected=*ast.StringLiteral, got=%T", key)
@@ -472,6 +694,31 @@ func TestParsingHashLiteralStringKeys(t *testing.T) {
 	}
 }
 
+func TestParsingHashLiteralOrder(t *testing.T) {
+	input := `{"b": 1, "a": 2, "c": 3}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("expression is wrong type. expected=*ast.HashLiteral, got=%T (%+[1]v)", stmt.Expression)
+	}
+
+	for i, expected := range []string{"b", "a", "c"} {
+		if key := hash.Pairs[i].Key.String(); key != expected {
+			t.Errorf("key %d is wrong. expected=%q, got=%q", i, expected, key)
+		}
+	}
+
+	if hash.String() != "{b:1, a:2, c:3}" {
+		t.Errorf("hash.String() wrong. got=%q", hash.String())
+	}
+}
+
 func TestParsingHashLiteralBooleanKeys(t *testing.T) {
 	input := `{true: "true", false: "false"}`
 
@@ -498,7 +745,8 @@ func TestParsingHashLiteralBooleanKeys(t *testing.T) {
 		false: "false",
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		literal, ok := key.(*ast.Boolean)
 		if !ok {
 			t.Errorf("key is wrong type. expected=*ast.StringLiteral, got=%T", key)
@@ -542,7 +790,8 @@ func TestParsingHashLiteralNumberKeys(t *testing.T) {
 		3.5: "three-point-five",
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		literal, ok := key.(*ast.NumberLiteral)
 		if !ok {
 			t.Errorf("key is wrong type. expected=*ast.NumberLiteral, got=%T", key)
@@ -593,7 +842,8 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is wrong type. expected=*ast.StringLiteral, got=%T", key)
@@ -841,6 +1091,33 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestFunctionLiteralWithName(t *testing.T) {
+	input := `let myFunction = fn() { };`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain correct number of statements. expected=%d, got=%d", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is wrong type. expected=*ast.LetStatement, got=%T", program.Statements[0])
+	}
+
+	function, ok := stmt.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Value is wrong type. expected=*ast.FunctionLiteral, got=%T", stmt.Value)
+	}
+
+	if function.Name != "myFunction" {
+		t.Errorf("function literal name wrong. expected=%q, got=%q", "myFunction", function.Name)
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := `add(1, 2 * 3, 4 + 5);`
 
@@ -908,7 +1185,109 @@ func TestUnterminatedString(t *testing.T) {
 	}
 
 	expected := "on line 1: unterminated string"
-	if errs[0] != expected {
+	if errs[0].Error() != expected {
 		t.Errorf("unexpected error message. expected=%q, got=%q", expected, errs[0])
 	}
 }
+
+func TestParseErrorRecovery(t *testing.T) {
+	input := `let x = ;
+let = 5;
+let y = 10;
+let f = fn(a) {
+  let b = a +;
+  if (b) { let = 1; }
+  b
+};
+let z = (1 + 2;
+}
+puts(f(1 2), {"a": 1}); let w = 2;
+let ok = 3`
+
+	expected := []struct {
+		message string
+		line    int
+		column  int
+		found   token.TokenType
+	}{
+		{"no prefix parse function for ; found", 1, 9, token.SEMICOLON},
+		{"expected next token to be IDENT, got = instead", 2, 5, token.EQ},
+		{"no prefix parse function for ; found", 5, 14, token.SEMICOLON},
+		{"expected next token to be IDENT, got = instead", 6, 16, token.EQ},
+		{"expected next token to be ), got ; instead", 9, 15, token.SEMICOLON},
+		{"no prefix parse function for } found", 10, 1, token.RBRACE},
+		{"expected next token to be ), got NUM instead", 11, 10, token.NUM},
+	}
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != len(expected) {
+		for _, err := range errors {
+			t.Logf("parser error: %q", err)
+		}
+		t.Fatalf("wrong number of errors. expected=%d, got=%d", len(expected), len(errors))
+	}
+
+	for i, tt := range expected {
+		err := errors[i]
+		if err.Message != tt.message {
+			t.Errorf("errors[%d] - wrong message. expected=%q, got=%q", i, tt.message, err.Message)
+		}
+		if err.Line != tt.line || err.Column != tt.column {
+			t.Errorf("errors[%d] - wrong position. expected=%d:%d, got=%d:%d", i, tt.line, tt.column, err.Line, err.Column)
+		}
+		if err.Found.Type != tt.found {
+			t.Errorf("errors[%d] - wrong token found. expected=%q, got=%q", i, tt.found, err.Found.Type)
+		}
+	}
+
+	if len(errors[1].Expected) != 1 || errors[1].Expected[0] != token.IDENT {
+		t.Errorf("errors[1] - wrong expected tokens. got=%v", errors[1].Expected)
+	}
+
+	var names []string
+	for _, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok {
+			names = append(names, let.Name.Value)
+		}
+	}
+
+	if fmt.Sprint(names) != "[y w ok]" {
+		t.Errorf("wrong statements parsed around errors. expected=%v, got=%v", []string{"y", "w", "ok"}, names)
+	}
+}
+
+func TestUnknownKeywordSuggestions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"lett x = 5;", []string{"on line 1: unknown keyword lett, did you mean let?"}},
+		{"let f = fn(x) {\n  retrun x;\n};", []string{"on line 2: unknown keyword retrun, did you mean return?"}},
+		{"thorw \"oops\"", []string{"on line 1: unknown keyword thorw, did you mean throw?"}},
+		{"if (x) { 1 } esle 2", []string{"on line 1: unknown keyword esle, did you mean else?"}},
+		{"lett x = 5;\nlet y = ;", []string{"on line 1: unknown keyword lett, did you mean let?", "on line 2: no prefix parse function for ; found"}},
+		{"foo\nbar", nil},
+		{"x y", nil},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expected) {
+			t.Errorf("wrong number of errors for %q. expected=%d, got=%d (%v)", tt.input, len(tt.expected), len(errors), errors)
+			continue
+		}
+
+		for i, msg := range tt.expected {
+			if errors[i].Error() != msg {
+				t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, msg, errors[i].Error())
+			}
+		}
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"os"
+	"strings"
 
+	"github.com/butlermatt/monlox/diagnostic"
 	"github.com/butlermatt/monlox/evaluator"
 	"github.com/butlermatt/monlox/lexer"
 	"github.com/butlermatt/monlox/object"
@@ -13,10 +16,17 @@ import (
 
 const prompt = ">> "
 
-// Start will begin a very simple REPL which reads from in and outputs response to out.
-func Start(in io.Reader, out io.Writer) {
+// Start will begin a very simple REPL which reads from in and outputs response to out. The file builtins
+// use fsys, and fail if it is nil.
+func Start(in io.Reader, out io.Writer, fsys evaluator.FS) {
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
+	eval := newEvaluator(fsys)
+	color := colorEnabled(out)
+
+	// Lines are numbered across the whole session, and diagnostics rendered against all of it, as an error
+	// may be raised in a function defined by an earlier line.
+	var source []string
 
 	for {
 		fmt.Fprintf(out, prompt)
@@ -26,25 +36,80 @@ func Start(in io.Reader, out io.Writer) {
 		}
 
 		line := scanner.Text()
-		l := lexer.New(line)
+		l := lexer.NewAt(line, len(source)+1)
 		p := parser.New(l)
+		source = append(source, line)
+		r := diagnostic.NewRenderer("<repl>", strings.Join(source, "\n"), color)
 
 		program := p.ParseProgram()
 		if len(p.Errors()) != 0 {
-			printParseErrors(out, p.Errors())
+			printParseErrors(out, r, p.Errors())
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
+		evaluated := eval.Eval(program, env)
 		if evaluated != nil {
+			if errObj, ok := evaluated.(*object.Error); ok {
+				printRuntimeError(out, r, errObj)
+				continue
+			}
+
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
 		}
 	}
 }
 
-func printParseErrors(out io.Writer, errors []string) {
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+// Run evaluates the whole program in input, writing a diagnostic for each parse error or for a runtime
+// error to out. name is the name of the source shown in diagnostics, and fsys is the filesystem of the file
+// builtins as for Start. Returns false if the program could not be parsed or failed with an error.
+func Run(name, input string, out io.Writer, fsys evaluator.FS) bool {
+	l := lexer.New(input)
+	p := parser.New(l)
+	r := diagnostic.NewRenderer(name, input, colorEnabled(out))
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParseErrors(out, r, p.Errors())
+		return false
+	}
+
+	evaluated := newEvaluator(fsys).Eval(program, object.NewEnvironment())
+	if errObj, ok := evaluated.(*object.Error); ok {
+		printRuntimeError(out, r, errObj)
+		return false
+	}
+
+	return true
+}
+
+// newEvaluator returns an Evaluator with the default limits whose file builtins use fsys.
+func newEvaluator(fsys evaluator.FS) *evaluator.Evaluator {
+	e := evaluator.New()
+	e.FS = fsys
+
+	return e
+}
+
+func printParseErrors(out io.Writer, r *diagnostic.Renderer, errors []*parser.ParseError) {
+	for _, err := range errors {
+		r.Render(out, diagnostic.FromParseError(err))
+	}
+}
+
+func printRuntimeError(out io.Writer, r *diagnostic.Renderer, err *object.Error) {
+	io.WriteString(out, err.StackTrace())
+	r.Render(out, diagnostic.FromError(err))
+}
+
+// colorEnabled reports whether out is a terminal which diagnostics should be highlighted on. Setting the
+// NO_COLOR environment variable disables highlighting.
+func colorEnabled(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
 	}
+
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
@@ -0,0 +1,27 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStartRendersErrorsFromEarlierLines(t *testing.T) {
+	input := `let f = fn(x) { x + "a" };
+let y = 1;
+f(2)
+`
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out, nil)
+
+	expected := `error[TypeError]: type mismatch: NUMBER + STRING
+ --> <repl>:1:19
+  |
+1 | let f = fn(x) { x + "a" };
+  |                   ^
+`
+	if !strings.Contains(out.String(), expected) {
+		t.Errorf("error not rendered against the line defining f.\nexpected to contain=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
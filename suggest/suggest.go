@@ -0,0 +1,56 @@
+package suggest
+
+import "sort"
+
+// Closest returns the candidate most similar to word, for use in "did you mean" messages. Returns an empty
+// string if no candidate is similar enough to be a likely misspelling. Ties are broken alphabetically.
+func Closest(word string, candidates []string) string {
+	sorted := append([]string(nil), candidates...)
+	sort.Strings(sorted)
+
+	// Allow roughly one edit per three characters, but never enough edits to replace the whole word.
+	max := (len(word) + 2) / 3
+	if max >= len(word) {
+		max = len(word) - 1
+	}
+
+	best, bestDist := "", max+1
+	for _, c := range sorted {
+		if c == word {
+			continue
+		}
+
+		if d := Distance(word, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+
+	return best
+}
+
+// Distance returns the Levenshtein distance between a and b: the number of single character insertions,
+// deletions and substitutions needed to change one into the other.
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
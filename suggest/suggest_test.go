@@ -0,0 +1,50 @@
+package suggest
+
+import "testing"
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"let", "let", 0},
+		{"lett", "let", 1},
+		{"retrun", "return", 2},
+		{"kitten", "sitting", 3},
+		{"héllo", "hello", 1},
+	}
+
+	for _, tt := range tests {
+		if d := Distance(tt.a, tt.b); d != tt.expected {
+			t.Errorf("Distance(%q, %q) wrong. expected=%d, got=%d", tt.a, tt.b, tt.expected, d)
+		}
+	}
+}
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"len", "first", "last", "rest", "push", "counter", "count"}
+
+	tests := []struct {
+		word     string
+		expected string
+	}{
+		{"lenn", "len"},
+		{"frist", "first"},
+		{"countr", "count"},
+		{"counte", "count"},
+		{"pusj", "push"},
+		{"x", ""},
+		{"ab", ""},
+		{"unrelated", ""},
+		{"len", ""},
+	}
+
+	for _, tt := range tests {
+		if c := Closest(tt.word, candidates); c != tt.expected {
+			t.Errorf("Closest(%q) wrong. expected=%q, got=%q", tt.word, tt.expected, c)
+		}
+	}
+}
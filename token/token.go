@@ -1,11 +1,14 @@
 package token
 
+import "sort"
+
 type TokenType string
 
 type Token struct {
 	Type    TokenType
 	Literal string
 	Line    int
+	Column  int // Byte offset of the start of the token within its line, starting at 1.
 }
 
 func New(ty TokenType, lit string, l int) Token {
@@ -23,12 +26,14 @@ const (
 	UTSTRING = "UNTERMINATED STRING" // Unterminated String
 
 	// Operators
-	EQ       = "="
-	PLUS     = "+"
-	MINUS    = "-"
-	BANG     = "!"
-	ASTERISK = "*"
-	SLASH    = "/"
+	EQ        = "="
+	PLUS      = "+"
+	MINUS     = "-"
+	BANG      = "!"
+	ASTERISK  = "*"
+	SLASH     = "/"
+	PIPE      = "|"
+	AMPERSAND = "&"
 
 	LT = "<"
 	GT = ">"
@@ -48,6 +53,7 @@ const (
 	RBRACE    = "}"
 	LBRACKET  = "["
 	RBRACKET  = "]"
+	SET_BRACE = "#{" // Opens a set literal.
 
 	// Keywords
 	FUNCTION = "FUNCTION"
@@ -59,18 +65,41 @@ const (
 	AND      = "AND"
 	OR       = "OR"
 	RETURN   = "RETURN"
+	THROW    = "THROW"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	FOR      = "FOR"
+	IN       = "IN"
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"and":    AND,
-	"or":     OR,
-	"return": RETURN,
+	"fn":      FUNCTION,
+	"let":     LET,
+	"true":    TRUE,
+	"false":   FALSE,
+	"if":      IF,
+	"else":    ELSE,
+	"and":     AND,
+	"or":      OR,
+	"return":  RETURN,
+	"throw":   THROW,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"for":     FOR,
+	"in":      IN,
+}
+
+// Keywords returns the reserved words of the language, sorted alphabetically.
+func Keywords() []string {
+	var words []string
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	return words
 }
 
 func LookupIdent(ident string) TokenType {